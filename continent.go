@@ -0,0 +1,150 @@
+package gopenflights
+
+import(
+	"fmt"
+	"strings"
+)
+
+// Continent names as returned by AirportRecord.Continent.
+const (
+	Africa = "Africa"
+	Antarctica = "Antarctica"
+	Asia = "Asia"
+	Europe = "Europe"
+	NorthAmerica = "North America"
+	Oceania = "Oceania"
+	SouthAmerica = "South America"
+	// UnknownContinent is returned for airports in countries without known continent.
+	UnknownContinent = "Unknown"
+)
+
+// continentCountries lists the country names as used by openflights per continent.
+// Central America and the Caribbean are part of North America. Transcontinental
+// countries are assigned to the continent holding their capital.
+var continentCountries = map[string][]string{
+	Africa: {
+		"Algeria", "Angola", "Benin", "Botswana", "Burkina Faso", "Burundi", "Cameroon",
+		"Cape Verde", "Central African Republic", "Chad", "Comoros", "Congo (Brazzaville)",
+		"Congo (Kinshasa)", "Cote d'Ivoire", "Djibouti", "Egypt", "Equatorial Guinea", "Eritrea",
+		"Eswatini", "Ethiopia", "Gabon", "Gambia", "Ghana", "Guinea", "Guinea-Bissau", "Kenya",
+		"Lesotho", "Liberia", "Libya", "Madagascar", "Malawi", "Mali", "Mauritania", "Mauritius",
+		"Mayotte", "Morocco", "Mozambique", "Namibia", "Niger", "Nigeria", "Reunion", "Rwanda",
+		"Saint Helena", "Sao Tome and Principe", "Senegal", "Seychelles", "Sierra Leone",
+		"Somalia", "South Africa", "South Sudan", "Sudan", "Swaziland", "Tanzania", "Togo",
+		"Tunisia", "Uganda", "Western Sahara", "Zambia", "Zimbabwe",
+	},
+	Antarctica: {
+		"Antarctica", "French Southern and Antarctic Lands", "South Georgia and the Islands",
+	},
+	Asia: {
+		"Afghanistan", "Armenia", "Azerbaijan", "Bahrain", "Bangladesh", "Bhutan",
+		"British Indian Ocean Territory", "Brunei", "Burma", "Cambodia", "China",
+		"Christmas Island", "Cocos (Keeling) Islands", "East Timor", "Georgia", "Hong Kong",
+		"India", "Indonesia", "Iran", "Iraq", "Israel", "Japan", "Jordan", "Kazakhstan", "Korea",
+		"Kuwait", "Kyrgyzstan", "Laos", "Lebanon", "Macau", "Malaysia", "Maldives", "Mongolia",
+		"Myanmar", "Nepal", "North Korea", "Oman", "Pakistan", "Palestine", "Philippines",
+		"Qatar", "Saudi Arabia", "Singapore", "South Korea", "Sri Lanka", "Syria", "Taiwan",
+		"Tajikistan", "Thailand", "Timor-Leste", "Turkey", "Turkmenistan",
+		"United Arab Emirates", "Uzbekistan", "Vietnam", "West Bank", "Yemen",
+	},
+	Europe: {
+		"Albania", "Andorra", "Austria", "Belarus", "Belgium", "Bosnia and Herzegovina",
+		"Bulgaria", "Croatia", "Cyprus", "Czech Republic", "Denmark", "Estonia",
+		"Faroe Islands", "Finland", "France", "Germany", "Gibraltar", "Greece", "Guernsey",
+		"Hungary", "Iceland", "Ireland", "Isle of Man", "Italy", "Jersey", "Kosovo", "Latvia",
+		"Liechtenstein", "Lithuania", "Luxembourg", "Macedonia", "Malta", "Moldova", "Monaco",
+		"Montenegro", "Netherlands", "North Macedonia", "Norway", "Poland", "Portugal",
+		"Romania", "Russia", "San Marino", "Serbia", "Slovakia", "Slovenia", "Spain",
+		"Svalbard", "Sweden", "Switzerland", "Ukraine", "United Kingdom",
+	},
+	NorthAmerica: {
+		"Anguilla", "Antigua and Barbuda", "Aruba", "Bahamas", "Barbados",
+		"Belize", "Bermuda", "Bonaire, Sint Eustatius and Saba", "British Virgin Islands",
+		"Canada", "Cayman Islands", "Costa Rica", "Cuba", "Curacao", "Dominica",
+		"Dominican Republic", "El Salvador", "Greenland", "Grenada", "Guadeloupe", "Guatemala",
+		"Haiti", "Honduras", "Jamaica", "Martinique", "Mexico", "Montserrat",
+		"Netherlands Antilles", "Nicaragua", "Panama", "Puerto Rico", "Saint Barthelemy",
+		"Saint Kitts and Nevis", "Saint Lucia", "Saint Martin", "Saint Pierre and Miquelon",
+		"Saint Vincent and the Grenadines", "Sint Maarten", "Trinidad and Tobago",
+		"Turks and Caicos Islands", "United States", "Virgin Islands",
+	},
+	Oceania: {
+		"American Samoa", "Australia", "Cook Islands", "Fiji", "French Polynesia", "Guam", "Johnston Atoll",
+		"Kiribati", "Marshall Islands", "Micronesia", "Midway Islands", "Nauru",
+		"New Caledonia", "New Zealand", "Niue", "Norfolk Island", "Northern Mariana Islands",
+		"Palau", "Papua New Guinea", "Samoa", "Solomon Islands", "Tonga", "Tuvalu", "Vanuatu",
+		"Wake Island", "Wallis and Futuna",
+	},
+	SouthAmerica: {
+		"Argentina", "Bolivia", "Brazil", "Chile", "Colombia", "Ecuador", "Falkland Islands",
+		"French Guiana", "Guyana", "Paraguay", "Peru", "Suriname", "Uruguay", "Venezuela",
+	},
+}
+
+// countryContinent maps normalized country names to their continent.
+var countryContinent = make(map[string]string)
+
+func init() {
+	for continent,countries := range continentCountries {
+		for _,c := range countries {
+			countryContinent[normalizeCountry(c)] = continent
+		}
+	}
+}
+
+// normalizeCountry returns the key of the given country name used for the continent lookup.
+func normalizeCountry(country string) string {
+	return strings.ToLower(strings.TrimSpace(country))
+}
+
+// Continent returns the continent the airport is located in.
+// UnknownContinent is returned if the country of the airport is not known.
+func (a *AirportRecord) Continent() string {
+	if c,ok := countryContinent[normalizeCountry(a.Country)]; ok {
+		return c
+	}
+	return UnknownContinent
+}
+
+// AirportsByContinent returns all airports grouped by their continent in the order
+// of the Airports slice. Airports in countries without known continent are grouped
+// under UnknownContinent.
+func (d *Database) AirportsByContinent() map[string][]*AirportRecord {
+	ret := make(map[string][]*AirportRecord)
+	for i := range d.Airports {
+		a := &d.Airports[i]
+		c := a.Continent()
+		ret[c] = append(ret[c],a)
+	}
+	return ret
+}
+
+// endpoints returns the source and destination airports of the route. Unresolved
+// airport references are looked up in the given database if it is not nil.
+func (r *RouteRecord) endpoints(d *Database) (src, dst *AirportRecord) {
+	src,dst = r.SourceAirportP,r.DestAirportP
+	if d != nil {
+		if src == nil {
+			src = d.Airport(r.SourceAirportId)
+		}
+		if dst == nil {
+			dst = d.Airport(r.DestAirportId)
+		}
+	}
+	return
+}
+
+// IsIntercontinental reports whether the route connects airports on different continents.
+// An error is returned if this cannot be determined because an airport of the route
+// is unknown or located in a country without known continent.
+func (r *RouteRecord) IsIntercontinental(d *Database) (bool, error) {
+	src,dst := r.endpoints(d)
+	if src == nil || dst == nil {
+		return false,fmt.Errorf("Airports of route %s -> %s are not resolved.",r.SourceCode(),r.DestCode())
+	}
+	sc,dc := src.Continent(),dst.Continent()
+	if sc == UnknownContinent || dc == UnknownContinent {
+		return false,fmt.Errorf("Continent of route %s -> %s is not known.",r.SourceCode(),r.DestCode())
+	}
+	return sc != dc,nil
+}
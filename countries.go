@@ -0,0 +1,103 @@
+package gopenflights
+
+import(
+	"context"
+	"fmt"
+	"io"
+)
+
+// CountryRecord represents a country object of the "countries.dat" file.
+type CountryRecord struct {
+	Name string
+	ISOCode string
+	DAFIFCode string
+}
+
+// Convert converts a string array read from the corresponding "countries.dat" csv file into the given CountryRecord object.
+func (r *CountryRecord) Convert(s []string) error {
+	l := len(s)
+	if l < 3 {
+		return fmt.Errorf("Invalid field count for Country record: %d/%d",l,3)
+	}
+	r.Name = field(s[0])
+	r.ISOCode = field(s[1])
+	r.DAFIFCode = field(s[2])
+	return nil
+}
+
+// LoadCountryData reads the country data from the given source.
+// The source could be either a localfile or http based URL and may be gzip compressed.
+// An error is returned if the source cannot be read.
+// Countries without ISO code are not added to the CountriesByISO index.
+func (d *Database) LoadCountryData(source string) error {
+	return d.LoadCountryDataContext(context.Background(),source)
+}
+
+// LoadCountryDataContext reads the country data from the given source like
+// LoadCountryData does. Http requests are aborted once the given context is done.
+func (d *Database) LoadCountryDataContext(ctx context.Context, source string) error {
+	d.logf("Loading Country data from \"%s\"",source)
+	data,err := d.loadCsvContext(ctx,source)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.setCountryData(source,data)
+	return nil
+}
+
+// LoadCountriesFrom reads the country data from the given reader like LoadCountryData does.
+// It allows to load embedded or in-memory data. The reader may be gzip compressed.
+func (d *Database) LoadCountriesFrom(r io.Reader) error {
+	data,err := d.readCsv(r)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.setCountryData("",data)
+	return nil
+}
+
+// setCountryData replaces the countries by the given csv lines read from source.
+// The caller must hold the write lock.
+func (d *Database) setCountryData(source string, data [][]string) {
+	d.Countries = make([]CountryRecord,len(data))
+	d.clearWarnings("Country")
+	n := d.convertRecords("Country",data,
+		func(n int) Record { return &d.Countries[n] },
+		func(dst, src int) { d.Countries[dst] = d.Countries[src] },
+		func(r Record, i int) bool { return true })
+	d.Countries = d.Countries[:n]
+	d.report.Countries = FileReport{source,n,len(data) - n}
+	d.reindexCountries()
+}
+
+// reindexCountries rebuilds the country indexes from the Countries slice.
+func (d *Database) reindexCountries() {
+	d.CountriesByName = make(map[string]*CountryRecord)
+	d.CountriesByISO = make(map[string]*CountryRecord)
+	for i := range d.Countries {
+		c := &d.Countries[i]
+		d.CountriesByName[c.Name] = c
+		if isCode(c.ISOCode) {
+			d.CountriesByISO[normalizeCode(c.ISOCode)] = c
+		}
+	}
+}
+
+// CountryByName returns the CountryRecord of the given country name as used by
+// the Country field of airports and airlines.
+func (d *Database) CountryByName(name string) *CountryRecord {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.CountriesByName[name]
+}
+
+// CountryByISO returns the CountryRecord of the given ISO 3166-1 alpha-2 code ignoring case.
+func (d *Database) CountryByISO(code string) *CountryRecord {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.CountriesByISO[normalizeCode(code)]
+}
@@ -0,0 +1,34 @@
+package gopenflights
+
+import(
+	"testing"
+)
+
+func TestLoadCountryData(t *testing.T) {
+	tdb,report,err := NewDatabaseWithReport("testdata/airports.dat","testdata/routes.dat","testdata/airlines.dat","testdata/countries.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Countries.Loaded != 8 || len(tdb.Countries) != 8 {
+		t.Errorf("Expected 8 countries but got %d",len(tdb.Countries))
+	}
+	// All airport countries of the test data are known.
+	for _,a := range tdb.Airports {
+		if tdb.CountryByName(a.Country) == nil {
+			t.Errorf("Unknown country %s of airport %d",a.Country,a.Id)
+		}
+	}
+	if c := tdb.CountryByName("United Kingdom"); c == nil || c.ISOCode != "GB" || c.DAFIFCode != "UK" {
+		t.Errorf("Unexpected country record of the United Kingdom: %v",c)
+	}
+	if c := tdb.CountryByISO("BQ"); c == nil || c.Name != "Bonaire, Saint Eustatius and Saba" || c.DAFIFCode != "" {
+		t.Errorf("Unexpected country record of BQ: %v",c)
+	}
+	if len(tdb.CountriesByISO) != 7 {
+		t.Errorf("Countries without ISO code must not be indexed.")
+	}
+
+	if tdb = loadTestDatabase(); len(tdb.Countries) != 0 || tdb.CountryByName("Germany") != nil {
+		t.Errorf("Countries must only be loaded if configured.")
+	}
+}
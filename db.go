@@ -4,38 +4,144 @@
 package gopenflights
 
 import(
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"strconv"
 	"net/http"
 	"io"
 	"strings"
+	"sync"
 	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
 	"log"
+	"math"
+	"time"
 )
 
 const (
-	DefaultBaseDatUrl = "https://raw.githubusercontent.com/jpatokal/openflights/master/data/"
-	DefaultAirportDatUrl = DefaultBaseDatUrl + "airports.dat"
-	DefaultRoutesDatUrl = DefaultBaseDatUrl + "routes.dat"
-	DefaultAirlineDatUrl = DefaultBaseDatUrl + "airlines.dat"
-	DefaultCacheDir = "/tmp"
 	DefaultAirportsFilename = "airports.dat"
 	DefaultAirlinesFilename = "airlines.dat"
 	DefaultRoutesFilename = "routes.dat"
 )
 
+// defaultBaseDatUrl is the initial value of DefaultBaseDatUrl.
+const defaultBaseDatUrl = "https://raw.githubusercontent.com/jpatokal/openflights/master/data/"
+
+// The URLs the default sources are downloaded from. They may be changed to point
+// to a mirror before a Database is opened. The file names like DefaultAirportsFilename
+// are appended to DefaultBaseDatUrl unless the URL of a file has been changed.
+// See also WithBaseURL.
+var (
+	DefaultBaseDatUrl = defaultBaseDatUrl
+	DefaultAirportDatUrl = defaultBaseDatUrl + DefaultAirportsFilename
+	DefaultRoutesDatUrl = defaultBaseDatUrl + DefaultRoutesFilename
+	DefaultAirlineDatUrl = defaultBaseDatUrl + DefaultAirlinesFilename
+)
+
+// defaultURL returns the URL the default source file is downloaded from given the
+// current value of its URL variable like DefaultAirportDatUrl.
+func defaultURL(url, filename string) string {
+	if url != defaultBaseDatUrl + filename {
+		return url
+	}
+	return strings.TrimSuffix(DefaultBaseDatUrl,"/") + "/" + filename
+}
+
+// DefaultCacheDir is the directory the default source files are cached in.
+// It is the "gopenflights" subdirectory of the user cache directory or, if that
+// is not available, of the temporary directory. It is created on demand.
+var DefaultCacheDir = defaultCacheDir()
+
+// defaultCacheDir determines the platform specific default cache directory.
+func defaultCacheDir() string {
+	dir,err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir,"gopenflights")
+}
+
+// DefaultHTTPClient is the http client used for all downloads unless configured otherwise.
+// In contrast to http.DefaultClient it gives up on hanging connections after 30 seconds.
+var DefaultHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
 // Database is an openflights database container.
+// The lookup methods Airport, AirportBy*, FindAirport, AirportsIn*, AirlineBy*, CountryBy*,
+// PlaneByIATA, Route, AllRoutes, FindRoute, FilterRoutes, Routes* (including RoutesGeo and
+// RoutesGeoJSON), HasDirectRoute, OperatingRoutes*, AirlinesBetweenCountries,
+// DuplicateIATACodes and LoadWarnings are safe for concurrent use with
+// loading, Refresh, Reindex, AddRoute and RemoveRoute*. Direct access to the fields as well
+// as all other methods must be synchronized by the caller if the database is modified
+// concurrently.
+// The code indexes (AirportsByIATA, AirlinesByICAO, ...) are keyed by upper case codes.
 type Database struct {
 	Routes []RouteRecord
 	Airports []AirportRecord
 	Airlines []AirlineRecord
+
+	// The index maps are maintained by the database and must not be modified by the
+	// caller. They are going to be unexported in a future version. Use the lookup
+	// methods like AirportByID, AirportByIATA or AirlineByID instead.
 	AirportsByIdIndex map[int]*AirportRecord
 	AirportsByIATA map[string]*AirportRecord
 	AirportsByICAO map[string]*AirportRecord
+	AirportsByAnyCode map[string]*AirportRecord
+	AirportsByCountry map[string][]*AirportRecord
+	AirportsByCity map[string][]*AirportRecord
 	AirlinesByIdIndex map[int]*AirlineRecord
+	AirlinesByIATA map[string]*AirlineRecord
+	AirlinesByICAO map[string]*AirlineRecord
+	Countries []CountryRecord
+	CountriesByName map[string]*CountryRecord
+	CountriesByISO map[string]*CountryRecord
+	Planes []PlaneRecord
+	PlanesByIATA map[string]*PlaneRecord
+
+	report LoadReport
+	warnings map[string][]string
+	duplicateIATA map[string][]int
+	routesByKey map[routeKey]*RouteRecord
+	routeSeq int
+	unknownAirlines UnknownAirlinePolicy
+	placeholders map[int]*AirlineRecord
+	airportSchema int
+	airportTree *kdTree
+	client *http.Client
+	logger Logger
+	airportTypes map[string]bool
+	comma rune
+	cfg *config
+	mu sync.RWMutex
+	treeMu sync.Mutex
+}
+
+// FileReport summarizes the loading of a single source file.
+type FileReport struct {
+	Source string
+	Loaded int
+	Skipped int
 }
 
+// LoadReport summarizes the loading of a Database.
+// It contains the record counts per source file and all warnings that have
+// been raised while loading the current data of each file.
+type LoadReport struct {
+	Airports FileReport
+	Airlines FileReport
+	Routes FileReport
+	Countries FileReport
+	Planes FileReport
+	Warnings []string
+}
+
+// Record is implemented by all record types that can be read from an openflights csv file.
 type Record interface {
 	Convert([]string) error
 }
@@ -47,20 +153,108 @@ type AirportRecord struct {
 	Lat, Long,Alt float64
 	Timezone float64
 	DST byte
+	Tz,Type,Source string
 
 	// references
 	DestRoutes map[*RouteRecord]bool `json:"-"`
 	SourceRoutes map[*RouteRecord]bool `json:"-"`
 }
 
+// DSTType is the daylight savings time rule of an airport.
+type DSTType byte
+
+// Daylight savings time rules as used by openflights.
+const (
+	DSTEurope DSTType = 'E'
+	DSTUSCanada DSTType = 'A'
+	DSTSouthAmerica DSTType = 'S'
+	DSTAustralia DSTType = 'O'
+	DSTNewZealand DSTType = 'Z'
+	DSTNone DSTType = 'N'
+	DSTUnknown DSTType = 'U'
+)
+
+// String returns the name of the DST rule.
+func (t DSTType) String() string {
+	switch t {
+	case DSTEurope:
+		return "Europe"
+	case DSTUSCanada:
+		return "US/Canada"
+	case DSTSouthAmerica:
+		return "South America"
+	case DSTAustralia:
+		return "Australia"
+	case DSTNewZealand:
+		return "New Zealand"
+	case DSTNone:
+		return "None"
+	}
+	return "Unknown"
+}
+
+// DSTRule returns the daylight savings time rule of the airport.
+// DSTUnknown is returned for missing or unrecognized DST values.
+func (a *AirportRecord) DSTRule() DSTType {
+	switch t := DSTType(a.DST); t {
+	case DSTEurope, DSTUSCanada, DSTSouthAmerica, DSTAustralia, DSTNewZealand, DSTNone:
+		return t
+	}
+	return DSTUnknown
+}
+
+// Location returns the time zone location of the airport based on its Tz database name.
+// An error is returned if the name is not specified or unknown.
+func (a *AirportRecord) Location() (*time.Location, error) {
+	if a.Tz == "" {
+		return nil,fmt.Errorf("Time zone of airport %d is not specified.",a.Id)
+	}
+	return time.LoadLocation(a.Tz)
+}
+
+// LocalTime returns the given time in the local time of the airport including DST as
+// given by its Tz database name. If the airport has no Tz name like in the legacy
+// schema, a fixed zone of its Timezone offset is used instead which ignores DST.
+// An error is returned if the Tz name is unknown.
+func (a *AirportRecord) LocalTime(t time.Time) (time.Time, error) {
+	if a.Tz == "" {
+		return t.In(a.fixedZone()),nil
+	}
+	loc,err := a.Location()
+	if err != nil {
+		return time.Time{},err
+	}
+	return t.In(loc),nil
+}
+
+// fixedZone returns a time zone of the Timezone offset of the airport named like "UTC+5:30".
+func (a *AirportRecord) fixedZone() *time.Location {
+	offset := int(math.Round(a.Timezone * 3600))
+	sign,abs := "+",offset
+	if offset < 0 {
+		sign,abs = "-",-offset
+	}
+	name := fmt.Sprintf("UTC%s%d",sign,abs / 3600)
+	if m := abs % 3600 / 60; m != 0 {
+		name += fmt.Sprintf(":%02d",m)
+	}
+	return time.FixedZone(name,offset)
+}
+
 // AirlineRecord represents an airline object.
 type AirlineRecord struct {
 	Id int
 	Name,Alias,IATA,ICAO,Callsign,Country string
 	Active bool
+
+	// references
+	Routes map[*RouteRecord]bool `json:"-"`
 }
 
 // RouteRecord represents a route object.
+// Routes do not embed any maps. The route sets of an airport are kept on the
+// AirportRecord (DestRoutes, SourceRoutes) and the AirlineRecord (Routes), so
+// Database.Routes is a dense slice of plain values that can be scanned efficiently.
 type RouteRecord struct {
 	Airline string
 	AirlineId int
@@ -76,48 +270,43 @@ type RouteRecord struct {
 	DestAirportP *AirportRecord `json:"-"`
 	SourceAirportP *AirportRecord `json:"-"`
 	AirlineP *AirlineRecord `json:"-"`
+
+	seq int // order in which the route has been loaded or added
 }
 
 // NewDatabase initializes a new openflights database.
+// See Open for a more flexible way of configuring the sources.
 // If no parameter are given, the source files are loaded via http from sourceforge and
-// will be cached under absolute path /tmp. If the files will be directly reloaded using
+// will be cached under DefaultCacheDir. If the files will be directly reloaded using
 // the Load* function, cache will always be ommitted.
 // If parameters are provided, first one is the "airport.dat", second the "routes.dat" and third
-// the "airline.dat" file.
+// the "airline.dat" file. An optional fourth parameter specifies the "countries.dat" file.
+// Errors are only logged and result in an empty database. Use Open to handle them.
 func NewDatabase(s...string) (db *Database) {
-	db = new(Database)
-	sl := len(s)
-
-	if sl == 0 {
-		// Check first if files are cached when not explicitly configured.
-		airportsC:= DefaultCacheDir + "/" + DefaultAirportsFilename
-		airlinesC:= DefaultCacheDir + "/" + DefaultAirlinesFilename
-		routesC := DefaultCacheDir + "/" + DefaultRoutesFilename
-
-		if _, err := os.Stat(airportsC); err != nil {
-			_ = DownloadFile(DefaultAirportDatUrl,airportsC)
-			//TODO: some more error handling here !
-		}
-		db.LoadAirportData(airportsC)
-
-		if _, err := os.Stat(airlinesC); err != nil {
-			_ = DownloadFile(DefaultAirlineDatUrl,airlinesC)
-			//TODO: some more error handling here !
-		}
-		db.LoadAirlineData(airlinesC)
-
-		if _, err := os.Stat(routesC); err != nil {
-			_ = DownloadFile(DefaultRoutesDatUrl,routesC)
-			//TODO: some more error handling here !
-		}
-		db.LoadRouteData(routesC)
+	db,_,err := NewDatabaseWithReport(s...)
+	if err != nil {
+		log.Printf("Could not initialize database: %s",err.Error())
+		db = new(Database)
+	}
+	return
+}
 
-	} else if sl == 3 {
-		db.LoadAirportData(s[0])
-		db.LoadAirlineData(s[2])
-		db.LoadRouteData(s[1])
-	} else {
-		panic("Invalid initialization parameter. Either none or all source files must be specified.")
+// NewDatabaseWithReport initializes a new openflights database the same way as NewDatabase
+// does. In addition it returns a report about the loaded and skipped records as well as an
+// error if the initialization failed.
+func NewDatabaseWithReport(s...string) (db *Database, report LoadReport, err error) {
+	switch len(s) {
+	case 0:
+		db,err = Open()
+	case 3:
+		db,err = Open(WithAirportsFile(s[0]),WithRoutesFile(s[1]),WithAirlinesFile(s[2]))
+	case 4:
+		db,err = Open(WithAirportsFile(s[0]),WithRoutesFile(s[1]),WithAirlinesFile(s[2]),WithCountriesFile(s[3]))
+	default:
+		err = fmt.Errorf("Invalid initialization parameter. Either none, three or four source files must be specified.")
+	}
+	if db != nil {
+		report = db.report
 	}
 	return
 }
@@ -125,42 +314,231 @@ func NewDatabase(s...string) (db *Database) {
 // DownloadFile downloads a file from a given surce URL.
 // The contents of the url will be written to a file which is given by the target parameter.
 func DownloadFile(source,target string) error{
-	out, err := os.Create(target)
-	defer out.Close()
+	return DownloadFileProgress(source,target,nil)
+}
+
+// DownloadFileProgress downloads a file like DownloadFile does. The progress callback
+// is invoked with the total number of bytes written so far after each chunk that has
+// been written to the target file. If progress is nil, no progress is reported.
+func DownloadFileProgress(source, target string, progress func(bytesWritten int64)) error {
+	return downloadFile(context.Background(),DefaultHTTPClient,source,target,progress,nil)
+}
+
+// progressWriter counts the bytes written to the underlying writer and reports them to progress.
+type progressWriter struct {
+	w io.Writer
+	n int64
+	progress func(int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n,err := p.w.Write(b)
+	p.n += int64(n)
+	p.progress(p.n)
+	return n,err
+}
+
+// statusError is returned by downloadFile for responses with a status other than 2xx.
+type statusError struct {
+	source string
+	code int
+	status string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("Download of \"%s\" failed: %s",e.source,e.status)
+}
+
+// retryable reports whether a download failed with the given error may succeed if
+// attempted again. Responses with a client error status other than 429 are final.
+func retryable(err error) bool {
+	var se *statusError
+	if errors.As(err,&se) {
+		return se.code >= 500 || se.code == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// downloadFile downloads a file from the given source URL using the given http client.
+// The target is only replaced once the download has been completed successfully and the
+// downloaded file passed the check, if given. Responses with a status other than 2xx are
+// treated as errors. The download is aborted once the given context is done. If progress
+// is not nil, it is called with the number of bytes written so far.
+func downloadFile(ctx context.Context, client *http.Client, source, target string, progress func(int64), check func(string) error) error {
+	req, err := http.NewRequestWithContext(ctx,"GET",source,nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
-	resp, err := http.Get(source)
 	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &statusError{source,resp.StatusCode,resp.Status}
+	}
+
+	// Download into a temporary file next to the target and move it into place
+	// on success, so that an interrupted download never leaves a truncated target.
+	out, err := os.CreateTemp(filepath.Dir(target),filepath.Base(target) + ".*.tmp")
 	if err != nil {
 		return err
 	}
-	_, err = io.Copy(out, resp.Body)
+	var w io.Writer = out
+	if progress != nil {
+		w = &progressWriter{w: out, progress: progress}
+	}
+	_, err = io.Copy(w, resp.Body)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && check != nil {
+		err = check(out.Name())
+	}
+	if err == nil {
+		err = os.Rename(out.Name(),target)
+	}
+	if err != nil {
+		os.Remove(out.Name())
+	}
 	return err
 }
 
+// checkDatFile checks that the given file is an openflights csv file with at least
+// minFields columns separated by comma. Empty files and HTML documents like error
+// pages are rejected. Only the first lines of the file are checked.
+func checkDatFile(path string, minFields int, comma rune) error {
+	f,err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	rc,err := decompress(f)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	br := bufio.NewReader(rc)
+	head,_ := br.Peek(512)
+	if len(bytes.TrimSpace(head)) == 0 {
+		return fmt.Errorf("File \"%s\" is empty.",path)
+	}
+	if strings.HasPrefix(http.DetectContentType(head),"text/html") {
+		return fmt.Errorf("File \"%s\" is an HTML document.",path)
+	}
+	reader := newCsvReader(br,comma)
+	for i := 0; i < 10; i++ {
+		v,err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("File \"%s\" is no valid csv file: %s",path,err.Error())
+		}
+		if len(v) < minFields {
+			return fmt.Errorf("Invalid field count in file \"%s\" @line %d: %d/%d",path,i+1,len(v),minFields)
+		}
+	}
+	return nil
+}
+
+// null is the marker openflights uses for fields without value.
+const null = "\\N"
+
+// field returns the value of the given csv field. Null fields result in an empty string.
+func field(s string) string {
+	if s == null {
+		return ""
+	}
+	return s
+}
+
+// atoi converts the given csv field into an int. Null fields result in 0.
+func atoi(s string) (int, error) {
+	if s == null {
+		return 0,nil
+	}
+	return strconv.Atoi(s)
+}
+
+// parseFloat converts the given csv field into a float. Null fields result in 0.
+func parseFloat(s string, bitSize int) (float64, error) {
+	if s == null {
+		return 0,nil
+	}
+	return strconv.ParseFloat(s,bitSize)
+}
+
 // Convert converts a string array read from the corresponding "routes.dat" csv file into the given RouteRecord object.
 func (r *RouteRecord) Convert(s []string) error{
 	l := len(s)
 	if l < 9 {
 		return fmt.Errorf("Invalid field count for Route record: %d/%d",l,9)
 	}
-	var ret error
-	r.Airline = s[0]
-	r.AirlineId,ret = strconv.Atoi(s[1])
-	r.SourceAirport = s[2]
-	r.SourceAirportId,ret = strconv.Atoi(s[3])
-	r.DestAirport = s[4]
-	r.DestAirportId,ret = strconv.Atoi(s[5])
+	var err error
+	var errs []error
+	r.Airline = field(s[0])
+	r.AirlineId,err = atoi(s[1])
+	errs = append(errs,err)
+	r.SourceAirport = field(s[2])
+	r.SourceAirportId,err = atoi(s[3])
+	errs = append(errs,err)
+	r.DestAirport = field(s[4])
+	r.DestAirportId,err = atoi(s[5])
+	errs = append(errs,err)
 	csb := []byte(s[6])
 	if len(csb) > 0 {
 		r.Codeshare = (csb[0] == 'Y')
 	} else {
 		r.Codeshare = false
 	}
-	r.Stops,ret = strconv.Atoi(s[7])
-	r.Equipment = s[8]
-	return ret
+	r.Stops,err = atoi(s[7])
+	errs = append(errs,err)
+	r.Equipment = field(s[8])
+	return errors.Join(errs...)
+}
+
+// airportCode returns the IATA code of the given airport or its ICAO code if it has none.
+func airportCode(a *AirportRecord) string {
+	if isCode(a.IATA) {
+		return a.IATA
+	}
+	return a.ICAO
+}
+
+// SourceCode returns the code of the source airport of the route. If the code has been
+// dropped by Database.CompactRoutes, it is taken from the resolved source airport.
+func (r *RouteRecord) SourceCode() string {
+	if r.SourceAirport == "" && r.SourceAirportP != nil {
+		return airportCode(r.SourceAirportP)
+	}
+	return r.SourceAirport
+}
+
+// DestCode returns the code of the destination airport of the route. If the code has been
+// dropped by Database.CompactRoutes, it is taken from the resolved destination airport.
+func (r *RouteRecord) DestCode() string {
+	if r.DestAirport == "" && r.DestAirportP != nil {
+		return airportCode(r.DestAirportP)
+	}
+	return r.DestAirport
+}
+
+// AirlineCode returns the code of the airline of the route. If the code has been dropped
+// by Database.CompactRoutes, it is taken from the resolved airline.
+func (r *RouteRecord) AirlineCode() string {
+	if r.Airline == "" && r.AirlineP != nil {
+		if isCode(r.AirlineP.IATA) {
+			return r.AirlineP.IATA
+		}
+		return r.AirlineP.ICAO
+	}
+	return r.Airline
+}
+
+// EquipmentCodes returns the aircraft type codes of the space separated Equipment field.
+// An empty slice is returned if no equipment is specified.
+func (r *RouteRecord) EquipmentCodes() []string {
+	return strings.Fields(r.Equipment)
 }
 
 // Convert converts a string array read from the corresponding "airline.dat" csv file into the given AirlineRecord object.
@@ -170,14 +548,16 @@ func (r *AirlineRecord) Convert(s []string) error{
 		return fmt.Errorf("Invalid field count for Airline record: %d/%d",l,8)
 	}
 
-	var ret error
-	r.Id,ret = strconv.Atoi(s[0])
-	r.Name = s[1]
-	r.Alias = s[2]
-	r.IATA = s[3]
-	r.ICAO = s[4]
-	r.Callsign = s[5]
-	r.Country = s[6]
+	var err error
+	var errs []error
+	r.Id,err = atoi(s[0])
+	errs = append(errs,err)
+	r.Name = field(s[1])
+	r.Alias = field(s[2])
+	r.IATA = field(s[3])
+	r.ICAO = field(s[4])
+	r.Callsign = field(s[5])
+	r.Country = field(s[6])
 
 	csb := []byte(s[7])
 	if len(csb) > 0 {
@@ -185,137 +565,863 @@ func (r *AirlineRecord) Convert(s []string) error{
 	} else {
 		r.Active = false
 	}
-	return ret
+
+	r.Routes = make(map[*RouteRecord]bool)
+	return errors.Join(errs...)
 }
 
 // Convert converts a string array read from the corresponding "airport.da"t csv file into the given AiportRecord object.
+// Both the legacy 11 column and the modern 14 column schema are supported.
+// All field conversion errors are combined into the returned error.
 func (r *AirportRecord) Convert(s []string) error{
 	l := len(s)
 	if l < 11 {
 		return fmt.Errorf("Invalid field count for Airport record: %d/%d",l,11)
 	}
-	var ret error
-	r.Id,ret = strconv.Atoi(s[0])
-	r.Name = s[1]
-	r.City = s[2]
-	r.Country = s[3]
-	r.IATA = s[4]
-	r.ICAO = s[5]
-	r.Lat,ret = strconv.ParseFloat(s[6],32)
-	r.Long,ret = strconv.ParseFloat(s[7],32)
-	r.Alt,ret = strconv.ParseFloat(s[8],32)
-	r.Timezone,ret = strconv.ParseFloat(s[9],32)
-	r.DST = []byte(s[10])[0]
+	var err error
+	var errs []error
+	r.Id,err = atoi(s[0])
+	errs = append(errs,err)
+	r.Name = field(s[1])
+	r.City = field(s[2])
+	r.Country = field(s[3])
+	r.IATA = field(s[4])
+	r.ICAO = field(s[5])
+	r.Lat,err = parseFloat(s[6],64)
+	errs = append(errs,err)
+	r.Long,err = parseFloat(s[7],64)
+	errs = append(errs,err)
+	r.Alt,err = parseFloat(s[8],64)
+	errs = append(errs,err)
+	r.Timezone,err = parseFloat(s[9],64)
+	errs = append(errs,err)
+	if dst := field(s[10]); len(dst) > 0 {
+		r.DST = dst[0]
+	} else {
+		r.DST = 0
+	}
+	// The modern schema has 3 additional columns.
+	r.Tz,r.Type,r.Source = "","",""
+	if l >= 14 {
+		r.Tz = field(s[11])
+		r.Type = field(s[12])
+		r.Source = field(s[13])
+	} else if l > 11 {
+		r.Tz = field(s[11])
+	}
 
 	r.DestRoutes = make(map[*RouteRecord]bool)
 	r.SourceRoutes = make(map[*RouteRecord]bool)
-	return ret
+	return errors.Join(errs...)
+}
+
+// Logger is used by a Database to log the progress and warnings of loading.
+// It is implemented by *log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// nopLogger is a Logger discarding all messages.
+type nopLogger struct{}
+
+func (nopLogger) Printf(string, ...interface{}) {}
+
+// NopLogger is a Logger that discards all messages.
+var NopLogger Logger = nopLogger{}
+
+// SetLogger sets the logger used for all messages of the database.
+// If logger is nil, the standard logger of the log package is used.
+// Use NopLogger to turn logging off.
+func (d *Database) SetLogger(logger Logger) {
+	d.logger = logger
+}
+
+// logf logs the given message using the configured logger.
+func (d *Database) logf(format string, v ...interface{}) {
+	if d.logger == nil {
+		log.Printf(format,v...)
+		return
+	}
+	d.logger.Printf(format,v...)
+}
+
+// Airport types as used by the Type field of the modern airport schema.
+const (
+	AirportTypeAirport = "airport"
+	AirportTypeStation = "station"
+	AirportTypePort = "port"
+	AirportTypeUnknown = "unknown"
+)
+
+// SetAirportTypes restricts the airports loaded subsequently to the given types like
+// AirportTypeAirport. Airports without type as of the legacy schema are always loaded.
+// Routes to and from skipped airports remain unresolved. If no types are given,
+// airports of all types are loaded.
+func (d *Database) SetAirportTypes(types ...string) {
+	d.airportTypes = make(map[string]bool,len(types))
+	for _,t := range types {
+		d.airportTypes[strings.ToLower(t)] = true
+	}
+}
+
+// SetDelimiter sets the field delimiter of the source files loaded subsequently like '\t'
+// or ';' for mirrors not using commas. If comma is 0, the default ',' is used.
+// Files written by the Save* functions are always comma separated.
+func (d *Database) SetDelimiter(comma rune) {
+	d.comma = comma
+}
+
+// UnknownAirlinePolicy defines how routes of airlines missing in the airline data are handled.
+type UnknownAirlinePolicy int
+
+const (
+	// UnknownAirlineKeep keeps the routes with a nil AirlineP. This is the default.
+	UnknownAirlineKeep UnknownAirlinePolicy = iota
+	// UnknownAirlineSkip skips the routes like routes without airport ids.
+	UnknownAirlineSkip
+	// UnknownAirlinePlaceholder keeps the routes and sets their AirlineP to a placeholder
+	// airline named "Unknown" with the id of the route and the airline code of the first of its
+	// routes. Placeholders are neither part of Airlines nor of the airline indexes.
+	UnknownAirlinePlaceholder
+)
+
+// SetUnknownAirlinePolicy sets how routes of unknown airline ids are handled by the route
+// loading functions and AddRoute. The airlines must be loaded before the routes.
+func (d *Database) SetUnknownAirlinePolicy(policy UnknownAirlinePolicy) {
+	d.unknownAirlines = policy
+}
+
+// newPlaceholderAirline returns a placeholder airline for the unknown airline of the given route.
+func newPlaceholderAirline(route *RouteRecord) *AirlineRecord {
+	a := &AirlineRecord{Id: route.AirlineId, Name: "Unknown", Routes: make(map[*RouteRecord]bool)}
+	if code := route.Airline; len(code) == 3 {
+		a.ICAO = code
+	} else if isCode(code) {
+		a.IATA = code
+	}
+	return a
+}
+
+// placeholderAirline returns the placeholder airline shared by all routes of the unknown
+// airline id of the given route.
+func (d *Database) placeholderAirline(route *RouteRecord) *AirlineRecord {
+	if d.placeholders == nil {
+		d.placeholders = make(map[int]*AirlineRecord)
+	}
+	a := d.placeholders[route.AirlineId]
+	if a == nil {
+		a = newPlaceholderAirline(route)
+		d.placeholders[route.AirlineId] = a
+	}
+	return a
+}
+
+// SetHTTPClient sets the http client used by the Load* functions for http based sources.
+// If client is nil, DefaultHTTPClient is used.
+func (d *Database) SetHTTPClient(client *http.Client) {
+	d.client = client
+}
+
+// httpClient returns the http client used by the database for downloads.
+func (d *Database) httpClient() *http.Client {
+	if d.client == nil {
+		return DefaultHTTPClient
+	}
+	return d.client
 }
 
 // loadCsv loads the contents of the given file or http-URL.
-func loadCsv(source string) (all [][]string){
-	var rc io.ReadCloser
+func (d *Database) loadCsv(source string) ([][]string, error) {
+	return d.loadCsvContext(context.Background(),source)
+}
+
+// loadCsvContext loads the contents of the given file or http-URL.
+// The given context is used for http requests.
+func (d *Database) loadCsvContext(ctx context.Context, source string) ([][]string, error) {
+	rc,err := d.openSource(ctx,source)
+	if err != nil {
+		return nil,err
+	}
+	defer rc.Close()
+	return newCsvReader(rc,d.comma).ReadAll()
+}
+
+// readCsv reads the contents of the given reader which may be gzip compressed.
+func (d *Database) readCsv(r io.Reader) ([][]string, error) {
+	rc,err := decompress(io.NopCloser(r))
+	if err != nil {
+		return nil,err
+	}
+	defer rc.Close()
+	return newCsvReader(rc,d.comma).ReadAll()
+}
+
+// openSource opens the given file or http-URL for reading.
+// Gzip compressed sources are detected by their magic bytes and decompressed transparently.
+// The given context is used for http requests. Responses with a status other than 2xx
+// are treated as errors and failed requests are retried as configured by WithRetry.
+func (d *Database) openSource(ctx context.Context, source string) (io.ReadCloser, error) {
 	if strings.HasPrefix(source,"http") {
-		resp, err := http.Get(source)
+		var resp *http.Response
+		err := d.retry(ctx,func() error {
+			req, err := http.NewRequestWithContext(ctx,"GET",source,nil)
+			if err != nil {
+				return err
+			}
+			if resp, err = d.httpClient().Do(req); err != nil {
+				return err
+			}
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				resp.Body.Close()
+				return &statusError{source,resp.StatusCode,resp.Status}
+			}
+			return nil
+		})
 		if err != nil {
-			panic(err)
+			return nil,err
 		}
-		rc = resp.Body
-	} else {
-		file, err := os.Open(source)
-		if err != nil {
-			panic(err)
+		return decompress(resp.Body)
+	}
+	f,err := os.Open(source)
+	if err != nil {
+		return nil,err
+	}
+	return decompress(f)
+}
+
+// sourceReader combines the reader of a source with the closer of its underlying stream.
+type sourceReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close closes the reader and the underlying stream.
+func (r *sourceReader) Close() (err error) {
+	for _,c := range r.closers {
+		if cerr := c.Close(); err == nil {
+			err = cerr
 		}
-		rc = file
 	}
+	return
+}
 
-	reader := csv.NewReader(rc)
-	reader.TrailingComma = true
-	all,err := reader.ReadAll()
+// decompress wraps the given stream into a gzip reader if it starts with the gzip magic bytes.
+// Uncompressed streams are read as they are. The stream is closed if the gzip header is invalid.
+func decompress(rc io.ReadCloser) (io.ReadCloser, error) {
+	br := bufio.NewReader(rc)
+	if magic,_ := br.Peek(2); len(magic) < 2 || magic[0] != 0x1f || magic[1] != 0x8b {
+		return &sourceReader{br,[]io.Closer{rc}},nil
+	}
+	gz,err := gzip.NewReader(br)
 	if err != nil {
-		log.Fatalf("Could not read source: %s",err.Error())
+		rc.Close()
+		return nil,err
+	}
+	return &sourceReader{gz,[]io.Closer{gz,rc}},nil
+}
+
+// newCsvReader returns a csv reader for openflights data files with fields separated
+// by comma. If comma is 0, fields are separated by ','.
+func newCsvReader(r io.Reader, comma rune) *csv.Reader {
+	reader := csv.NewReader(r)
+	reader.TrailingComma = true
+	if comma != 0 {
+		reader.Comma = comma
+	}
+	return reader
+}
+
+// LoadWarnings returns all warnings raised while loading the current data such as
+// skipped records or unresolved references.
+func (d *Database) LoadWarnings() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return append([]string{},d.report.Warnings...)
+}
+
+// warningKinds are the record kinds warnings are recorded for in the order of the load report.
+var warningKinds = []string{"Airport","Airline","Route","Country","Plane"}
+
+// warnf logs the given warning and records it in the load report as warning of the
+// data of the given record kind like "Airport". Warnings already recorded for the kind,
+// like the ones raised again by Reindex, are only logged. Warnings of any other kind
+// are only logged.
+func (d *Database) warnf(kind, format string, v ...interface{}) {
+	msg := fmt.Sprintf(format,v...)
+	d.logf("%s",msg)
+	if kind == "" {
+		return
+	}
+	for _,w := range d.warnings[kind] {
+		if w == msg {
+			return
+		}
+	}
+	if d.warnings == nil {
+		d.warnings = make(map[string][]string)
+	}
+	d.warnings[kind] = append(d.warnings[kind],msg)
+	d.report.Warnings = append(d.report.Warnings,msg)
+}
+
+// clearWarnings removes the recorded warnings of the given record kind. It is called
+// whenever the data of the kind is replaced.
+func (d *Database) clearWarnings(kind string) {
+	delete(d.warnings,kind)
+	d.report.Warnings = nil
+	for _,k := range warningKinds {
+		d.report.Warnings = append(d.report.Warnings,d.warnings[k]...)
+	}
+}
+
+// convertWorkers is the number of goroutines converting csv lines in parallel.
+// If it is not positive, runtime.GOMAXPROCS workers are used.
+var convertWorkers = 0
+
+// convertRecords converts the given csv lines into records and hands the successfully
+// converted ones to keep together with their line index in the order of the lines.
+// keep decides whether the record is retained. It returns the number of retained records.
+//
+// If move is nil, the lines are converted one after the other. The record to convert into
+// is obtained from at, which is passed the number of records retained so far.
+// Otherwise line i is converted into the record at(i) by a pool of goroutines first and
+// the retained records are then compacted by move, which copies the record at index src
+// to index dst, while keep is called serially.
+func (d *Database) convertRecords(kind string, data [][]string, at func(int) Record, move func(dst, src int), keep func(Record,int) bool) (n int) {
+	if move == nil {
+		for i,v := range data {
+			r := at(n)
+			if err := r.Convert(v); err != nil {
+				d.warnf(kind,"Cannot convert %sRecord @line %d: %s",kind,i+1,err.Error())
+			} else if keep(r,i) {
+				n++
+			}
+		}
+		return
+	}
+
+	errs := convertParallel(data,at)
+	for i,err := range errs {
+		if err != nil {
+			d.warnf(kind,"Cannot convert %sRecord @line %d: %s",kind,i+1,err.Error())
+			continue
+		}
+		if n != i {
+			move(n,i)
+		}
+		if keep(at(n),i) {
+			n++
+		}
 	}
 	return
 }
 
+// convertParallel converts each csv line i into the record at(i) using a pool of
+// convertWorkers goroutines. It returns the conversion error of each line.
+func convertParallel(data [][]string, at func(int) Record) []error {
+	errs := make([]error,len(data))
+	workers := convertWorkers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	chunk := (len(data) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for start := 0; start < len(data); start += chunk {
+		end := start + chunk
+		if end > len(data) {
+			end = len(data)
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				errs[i] = at(i).Convert(data[i])
+			}
+		}(start,end)
+	}
+	wg.Wait()
+	return errs
+}
+
+// LoadRecords reads arbitrary records from the given source.
+// For each csv line a new record is obtained from factory, converted and handed
+// over to collect. Lines that cannot be converted are logged and skipped.
+// The source could be either a localfile or http based URL and may be gzip compressed.
+// An error is returned if the source cannot be read.
+func (d *Database) LoadRecords(source string, factory func() Record, collect func(Record)) error {
+	d.logf("Loading records from \"%s\"",source)
+	data,err := d.loadCsv(source)
+	if err != nil {
+		return err
+	}
+	d.convertRecords("",data,
+		func(int) Record { return factory() },
+		nil,
+		func(r Record, i int) bool {
+			collect(r)
+			return true
+		})
+	return nil
+}
+
 // LoadAirportData reads the airport data from the given source.
-// The source could be either a localfile or http based URL.
-func (d *Database) LoadAirportData(source string){
-	log.Printf("Loading Airport data from \"%s\"",source)
-	data := loadCsv(source)
+// The source could be either a localfile or http based URL and may be gzip compressed.
+// An error is returned if the source cannot be read.
+// Airports without IATA or ICAO code (empty or "\N") are not added to the
+// respective code index.
+func (d *Database) LoadAirportData(source string) error {
+	return d.LoadAirportDataContext(context.Background(),source)
+}
+
+// LoadAirportDataContext reads the airport data from the given source like
+// LoadAirportData does. Http requests are aborted once the given context is done.
+func (d *Database) LoadAirportDataContext(ctx context.Context, source string) error {
+	d.logf("Loading Airport data from \"%s\"",source)
+	data,err := d.loadCsvContext(ctx,source)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.setAirportData(source,data)
+	return nil
+}
+
+// LoadAirportsFrom reads the airport data from the given reader like LoadAirportData does.
+// It allows to load embedded or in-memory data. The reader may be gzip compressed.
+func (d *Database) LoadAirportsFrom(r io.Reader) error {
+	data,err := d.readCsv(r)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.setAirportData("",data)
+	return nil
+}
+
+// setAirportData replaces the airports by the given csv lines read from source.
+// Airports of types not configured by SetAirportTypes are skipped.
+// The caller must hold the write lock.
+func (d *Database) setAirportData(source string, data [][]string) {
 	d.Airports =  make([]AirportRecord,len(data))
+	d.clearWarnings("Airport")
+	d.resetAirportIndexes()
+	d.airportSchema = AirportSchemaModern
+	if len(data) > 0 && len(data[0]) < AirportSchemaModern {
+		d.airportSchema = AirportSchemaLegacy
+	}
+	n := d.convertRecords("Airport",data,
+		func(n int) Record { return &d.Airports[n] },
+		func(dst, src int) { d.Airports[dst] = d.Airports[src] },
+		func(r Record, i int) bool {
+			a := r.(*AirportRecord)
+			if len(d.airportTypes) > 0 && a.Type != "" && !d.airportTypes[strings.ToLower(a.Type)] {
+				return false
+			}
+			d.indexAirport(a)
+			return true
+		})
+	d.Airports = d.Airports[:n]
+	d.report.Airports = FileReport{source,n,len(data) - n}
+	d.indexAnyCode()
+}
+
+// resetAirportIndexes clears all airport indexes.
+func (d *Database) resetAirportIndexes() {
 	d.AirportsByIdIndex = make(map[int]*AirportRecord)
 	d.AirportsByIATA = make(map[string]*AirportRecord)
 	d.AirportsByICAO = make(map[string]*AirportRecord)
-	for i,v := range data {
-		err := d.Airports[i].Convert(v)
-		if (err != nil) {
-			log.Printf("Cannot convert AirportRecord: %s",err.Error())
-		} else {
-			d.AirportsByIdIndex[d.Airports[i].Id] = &d.Airports[i]
-			d.AirportsByIATA[d.Airports[i].IATA] = &d.Airports[i]
-			d.AirportsByICAO[d.Airports[i].ICAO] = &d.Airports[i]
-			d.Airports[i].DestRoutes = make(map[*RouteRecord]bool)
-			d.Airports[i].SourceRoutes = make(map[*RouteRecord]bool)
+	d.AirportsByCountry = make(map[string][]*AirportRecord)
+	d.AirportsByCity = make(map[string][]*AirportRecord)
+	d.duplicateIATA = make(map[string][]int)
+	d.resetTree()
+}
+
+// indexAirport adds the given airport to the id, IATA, ICAO, country and city indexes.
+func (d *Database) indexAirport(a *AirportRecord) {
+	d.AirportsByIdIndex[a.Id] = a
+	if iata := normalizeCode(a.IATA); isCode(iata) {
+		if p := d.AirportsByIATA[iata]; p != nil {
+			d.warnf("Airport","IATA code \"%s\" of airportId %d is already used by airportId %d.",iata,a.Id,p.Id)
+			if len(d.duplicateIATA[iata]) == 0 {
+				d.duplicateIATA[iata] = []int{p.Id}
+			}
+			d.duplicateIATA[iata] = append(d.duplicateIATA[iata],a.Id)
 		}
+		d.AirportsByIATA[iata] = a
+	}
+	if icao := normalizeCode(a.ICAO); isCode(icao) {
+		d.AirportsByICAO[icao] = a
+	}
+	d.AirportsByCountry[a.Country] = append(d.AirportsByCountry[a.Country],a)
+	d.AirportsByCity[a.City] = append(d.AirportsByCity[a.City],a)
+}
+
+// reindexAirports rebuilds all airport indexes from the Airports slice.
+func (d *Database) reindexAirports() {
+	d.resetAirportIndexes()
+	for i := range d.Airports {
+		d.indexAirport(&d.Airports[i])
+	}
+	d.indexAnyCode()
+}
+
+// DuplicateIATACodes returns all IATA codes that are used by more than one airport
+// mapped to the ids of these airports in the order of the source file.
+// AirportsByIATA and AirportByIATA return the last of them.
+func (d *Database) DuplicateIATACodes() map[string][]int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	ret := make(map[string][]int,len(d.duplicateIATA))
+	for code,ids := range d.duplicateIATA {
+		ret[code] = append([]int{},ids...)
+	}
+	return ret
+}
+
+// normalizeCode returns the key of the given IATA or ICAO code used by all code indexes.
+// Codes are indexed and looked up in upper case.
+func normalizeCode(code string) string {
+	return strings.ToUpper(code)
+}
+
+// isCode reports whether the given IATA or ICAO code is actually specified.
+// Empty codes as well as the placeholders "\N", "-" and "N/A" are not.
+func isCode(code string) bool {
+	return code != "" && code != null && code != "-" && code != "N/A"
+}
+
+// indexAnyCode builds the AirportsByAnyCode index. IATA codes are indexed first
+// so that an ICAO code wins over an identical IATA code of another airport.
+func (d *Database) indexAnyCode() {
+	d.AirportsByAnyCode = make(map[string]*AirportRecord)
+	for i := range d.Airports {
+		if a := &d.Airports[i]; isCode(a.IATA) {
+			d.AirportsByAnyCode[normalizeCode(a.IATA)] = a
+		}
+	}
+	for i := range d.Airports {
+		a := &d.Airports[i]
+		icao := normalizeCode(a.ICAO)
+		if !isCode(icao) {
+			continue
+		}
+		if p := d.AirportsByAnyCode[icao]; p != nil && p != a && normalizeCode(p.IATA) == icao {
+			d.warnf("Airport","Code \"%s\" is IATA code of airportId %d and ICAO code of airportId %d. Using ICAO.",icao,p.Id,a.Id)
+		}
+		d.AirportsByAnyCode[icao] = a
 	}
 }
 
 // LoadAirlineDate reads the airline data from the given source.
-// The source could be either a localfile or http based URL.
-func (d *Database) LoadAirlineData(source string) {
-	log.Printf("Loading Airline data from \"%s\"",source)
-	data := loadCsv(source)
+// The source could be either a localfile or http based URL and may be gzip compressed.
+// An error is returned if the source cannot be read.
+func (d *Database) LoadAirlineData(source string) error {
+	return d.LoadAirlineDataContext(context.Background(),source)
+}
+
+// LoadAirlineDataContext reads the airline data from the given source like
+// LoadAirlineData does. Http requests are aborted once the given context is done.
+func (d *Database) LoadAirlineDataContext(ctx context.Context, source string) error {
+	d.logf("Loading Airline data from \"%s\"",source)
+	data,err := d.loadCsvContext(ctx,source)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.setAirlineData(source,data)
+	return nil
+}
+
+// LoadAirlinesFrom reads the airline data from the given reader like LoadAirlineData does.
+// It allows to load embedded or in-memory data. The reader may be gzip compressed.
+func (d *Database) LoadAirlinesFrom(r io.Reader) error {
+	data,err := d.readCsv(r)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.setAirlineData("",data)
+	return nil
+}
+
+// setAirlineData replaces the airlines by the given csv lines read from source.
+// The caller must hold the write lock.
+func (d *Database) setAirlineData(source string, data [][]string) {
 	d.Airlines =  make([]AirlineRecord,len(data))
+	d.clearWarnings("Airline")
+	d.resetAirlineIndexes()
+	n := d.convertRecords("Airline",data,
+		func(n int) Record { return &d.Airlines[n] },
+		func(dst, src int) { d.Airlines[dst] = d.Airlines[src] },
+		func(r Record, i int) bool {
+			d.indexAirline(r.(*AirlineRecord))
+			return true
+		})
+	d.Airlines = d.Airlines[:n]
+	d.report.Airlines = FileReport{source,n,len(data) - n}
+}
+
+// resetAirlineIndexes clears all airline indexes.
+func (d *Database) resetAirlineIndexes() {
 	d.AirlinesByIdIndex = make(map[int]*AirlineRecord)
-	for i,v := range data {
-		err := d.Airlines[i].Convert(v)
-		if (err != nil) {
-			log.Printf("Cannot convert AirlineRecord: %s",err.Error())
-		} else {
-			d.AirlinesByIdIndex[d.Airlines[i].Id] = &d.Airlines[i]
-		}
+	d.AirlinesByIATA = make(map[string]*AirlineRecord)
+	d.AirlinesByICAO = make(map[string]*AirlineRecord)
+}
+
+// indexAirline adds the given airline to the id, IATA and ICAO indexes.
+func (d *Database) indexAirline(a *AirlineRecord) {
+	d.AirlinesByIdIndex[a.Id] = a
+	// Codes of defunct airlines are often reused. Prefer active airlines.
+	iata,icao := normalizeCode(a.IATA),normalizeCode(a.ICAO)
+	if prev := d.AirlinesByIATA[iata]; isCode(iata) && (prev == nil || a.Active || !prev.Active) {
+		d.AirlinesByIATA[iata] = a
+	}
+	if prev := d.AirlinesByICAO[icao]; isCode(icao) && (prev == nil || a.Active || !prev.Active) {
+		d.AirlinesByICAO[icao] = a
+	}
+}
+
+// reindexAirlines rebuilds all airline indexes from the Airlines slice.
+func (d *Database) reindexAirlines() {
+	d.resetAirlineIndexes()
+	for i := range d.Airlines {
+		d.indexAirline(&d.Airlines[i])
 	}
 }
 
 // LoadRouteData reads the route data from the given source.
-// The source could be either a localfile or http based URL.
-func (d *Database) LoadRouteData(source string) {
-	log.Printf("Loading Route data from \"%s\"",source)
-	data := loadCsv(source)
+// The source could be either a localfile or http based URL and may be gzip compressed.
+// An error is returned if the source cannot be read.
+func (d *Database) LoadRouteData(source string) error {
+	return d.LoadRouteDataContext(context.Background(),source)
+}
+
+// LoadRouteDataContext reads the route data from the given source like
+// LoadRouteData does. Http requests are aborted once the given context is done.
+func (d *Database) LoadRouteDataContext(ctx context.Context, source string) error {
+	d.logf("Loading Route data from \"%s\"",source)
+	data,err := d.loadCsvContext(ctx,source)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.setRouteData(source,data)
+	return nil
+}
+
+// LoadRoutesFrom reads the route data from the given reader like LoadRouteData does.
+// It allows to load embedded or in-memory data. The reader may be gzip compressed.
+func (d *Database) LoadRoutesFrom(r io.Reader) error {
+	data,err := d.readCsv(r)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.setRouteData("",data)
+	return nil
+}
+
+// setRouteData replaces the routes by the given csv lines read from source.
+// The caller must hold the write lock.
+func (d *Database) setRouteData(source string, data [][]string) {
 	d.Routes =  make([]RouteRecord,len(data))
-	idx := 0
-	for i,v := range data {
-		err := d.Routes[idx].Convert(v)
-		route := &(d.Routes[idx])
-		if (err != nil) {
-			log.Printf("Cannot convert RouteRecord: %s",err.Error())
-		} else if route.DestAirportId == 0 {
-			log.Printf("Destination aiportId of \"%s\" @line %d is not specified. Ignoring route.",route.DestAirport,i+1)
-		} else if route.SourceAirportId == 0 {
-			log.Printf("Source aiportId of \"%s\" @line %d is not specified. Ignoring route.",route.SourceAirport,i+1)
-		} else {
-			idx++
-			route.DestAirportP = d.AirportsByIdIndex[route.DestAirportId]
-			route.SourceAirportP = d.AirportsByIdIndex[route.SourceAirportId]
-			route.AirlineP = d.AirlinesByIdIndex[route.AirlineId]
-
-			if route.DestAirportP != nil {
-				route.DestAirportP.DestRoutes[route] = true
-			} else {
-				log.Printf("Could not find destination airportId: %d/%s",route.DestAirportId,route.DestAirport)
+	d.clearWarnings("Route")
+	d.routesByKey = make(map[routeKey]*RouteRecord,len(data))
+	d.placeholders = nil
+	n := d.convertRecords("Route",data,
+		func(n int) Record { return &d.Routes[n] },
+		func(dst, src int) { d.Routes[dst] = d.Routes[src] },
+		func(r Record, i int) bool {
+			route := r.(*RouteRecord)
+			if route.DestAirportId == 0 {
+				d.warnf("Route","Destination aiportId of \"%s\" @line %d is not specified. Ignoring route.",route.DestAirport,i+1)
+				return false
+			} else if route.SourceAirportId == 0 {
+				d.warnf("Route","Source aiportId of \"%s\" @line %d is not specified. Ignoring route.",route.SourceAirport,i+1)
+				return false
+			} else if d.unknownAirlines == UnknownAirlineSkip && d.AirlinesByIdIndex[route.AirlineId] == nil {
+				d.warnf("Route","Could not find airlineId %d/%s @line %d. Ignoring route.",route.AirlineId,route.Airline,i+1)
+				return false
 			}
-
-			if route.SourceAirportP != nil {
-				route.SourceAirportP.SourceRoutes[route] = true
-			} else {
-				log.Printf("Could not find source airportId: %d/%s",route.SourceAirportId,route.SourceAirport)
+			d.linkRoute(route)
+			if route.DestAirportP == nil {
+				d.warnf("Route","Could not find destination airportId: %d/%s @line %d",route.DestAirportId,route.DestAirport,i+1)
+			}
+			if route.SourceAirportP == nil {
+				d.warnf("Route","Could not find source airportId: %d/%s @line %d",route.SourceAirportId,route.SourceAirport,i+1)
 			}
+			return true
+		})
+	d.Routes = d.Routes[:n]
+	d.report.Routes = FileReport{source,n,len(data) - n}
+}
+
+// StreamRouteData reads the route data from the given source line by line and calls fn
+// for each route without adding it to the database. The airport and airline references
+// of the routes are resolved with the currently loaded data but the routes are not
+// registered at them. Invalid routes are skipped the same way as by LoadRouteData.
+// Reading stops at the first error returned by fn which is then returned.
+// Skipped routes are logged but not recorded in the load report.
+func (d *Database) StreamRouteData(source string, fn func(RouteRecord) error) error {
+	rc,err := d.openSource(context.Background(),source)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	reader := newCsvReader(rc,d.comma)
+	for line := 1; ; line++ {
+		v,err := reader.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var route RouteRecord
+		if err = route.Convert(v); err != nil {
+			d.logf("Cannot convert RouteRecord @line %d: %s",line,err.Error())
+			continue
+		}
+		if route.DestAirportId == 0 || route.SourceAirportId == 0 {
+			d.logf("Aiport ids of \"%s\" -> \"%s\" @line %d are not specified. Ignoring route.",route.SourceAirport,route.DestAirport,line)
+			continue
+		}
+		d.mu.RLock()
+		route.DestAirportP = d.AirportsByIdIndex[route.DestAirportId]
+		route.SourceAirportP = d.AirportsByIdIndex[route.SourceAirportId]
+		route.AirlineP = d.AirlinesByIdIndex[route.AirlineId]
+		d.mu.RUnlock()
+		if route.AirlineP == nil && d.unknownAirlines == UnknownAirlineSkip {
+			d.logf("Could not find airlineId %d/%s @line %d. Ignoring route.",route.AirlineId,route.Airline,line)
+			continue
+		} else if route.AirlineP == nil && d.unknownAirlines == UnknownAirlinePlaceholder {
+			route.AirlineP = newPlaceholderAirline(&route)
+		}
+		if err = fn(route); err != nil {
+			return err
 		}
 	}
 }
 
+// routeKey is the key of the index of routes by airline, source and destination airport id.
+type routeKey struct {
+	airline,src,dst int
+}
+
+// linkRoute resolves the airport and airline references of the given route and
+// registers the route at its airline and its source and destination airports.
+// The route is added to the route key index unless there is an earlier route with the same key.
+// Routes linked for the first time are numbered in the order they have been loaded or added.
+func (d *Database) linkRoute(route *RouteRecord) {
+	if d.routesByKey == nil {
+		d.routesByKey = make(map[routeKey]*RouteRecord)
+	}
+	if route.seq == 0 {
+		d.routeSeq++
+		route.seq = d.routeSeq
+	}
+	key := routeKey{route.AirlineId,route.SourceAirportId,route.DestAirportId}
+	if first := d.routesByKey[key]; first == nil || route.seq < first.seq {
+		d.routesByKey[key] = route
+	}
+
+	route.DestAirportP = d.AirportsByIdIndex[route.DestAirportId]
+	route.SourceAirportP = d.AirportsByIdIndex[route.SourceAirportId]
+	route.AirlineP = d.AirlinesByIdIndex[route.AirlineId]
+	if route.AirlineP == nil && d.unknownAirlines == UnknownAirlinePlaceholder {
+		route.AirlineP = d.placeholderAirline(route)
+	}
+
+	if route.AirlineP != nil {
+		route.AirlineP.Routes[route] = true
+	}
+
+	if route.DestAirportP != nil {
+		route.DestAirportP.DestRoutes[route] = true
+	}
+
+	if route.SourceAirportP != nil {
+		route.SourceAirportP.SourceRoutes[route] = true
+	}
+}
+
+// relinkRoutes clears the route sets of all airports and airlines and links all
+// routes again. This is required whenever the route records have been moved.
+func (d *Database) relinkRoutes() {
+	d.routesByKey = make(map[routeKey]*RouteRecord,len(d.Routes))
+	d.placeholders = nil
+	for i := range d.Airports {
+		d.Airports[i].DestRoutes = make(map[*RouteRecord]bool)
+		d.Airports[i].SourceRoutes = make(map[*RouteRecord]bool)
+	}
+	for i := range d.Airlines {
+		d.Airlines[i].Routes = make(map[*RouteRecord]bool)
+	}
+	for i := range d.Routes {
+		d.linkRoute(&d.Routes[i])
+	}
+}
+
+// Reindex rebuilds all indexes and relinks all routes from the current record slices.
+// Call it after records have been appended to or edited in the Airports, Airlines,
+// Routes, Countries or Planes slices directly. All previously obtained record pointers
+// remain valid as long as the slices have not been reallocated.
+func (d *Database) Reindex() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.reindex()
+}
+
+// reindex rebuilds all indexes and relinks all routes.
+func (d *Database) reindex() {
+	d.reindexAirports()
+	d.reindexAirlines()
+	d.reindexCountries()
+	d.reindexPlanes()
+	d.relinkRoutes()
+}
+
+// AddRoute adds the given route to the database and links it to its airline and airports
+// the same way LoadRouteData does. Source and destination airportId of the route must be
+// specified and refer to loaded airports. The airline may be unknown unless UnknownAirlineSkip
+// is configured by SetUnknownAirlinePolicy.
+// If the route slice needs to grow, all route records are moved and previously obtained
+// RouteRecord pointers become stale.
+func (d *Database) AddRoute(r RouteRecord) error {
+	if r.SourceAirportId == 0 || r.DestAirportId == 0 {
+		return fmt.Errorf("Source and destination airportId of route %s -> %s must be specified.",r.SourceCode(),r.DestCode())
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.AirportsByIdIndex[r.SourceAirportId] == nil {
+		return fmt.Errorf("Unknown source airportId: %d",r.SourceAirportId)
+	}
+	if d.AirportsByIdIndex[r.DestAirportId] == nil {
+		return fmt.Errorf("Unknown destination airportId: %d",r.DestAirportId)
+	}
+	if d.unknownAirlines == UnknownAirlineSkip && d.AirlinesByIdIndex[r.AirlineId] == nil {
+		return fmt.Errorf("Unknown airlineId: %d",r.AirlineId)
+	}
+	r.seq = 0
+	c := cap(d.Routes)
+	d.Routes = append(d.Routes,r)
+	if cap(d.Routes) != c {
+		d.relinkRoutes()
+	} else {
+		d.linkRoute(&d.Routes[len(d.Routes) - 1])
+	}
+	return nil
+}
+
 // keys returns a slice of RouteRecord pointers of the given map.
+// The slice is sorted by routeLess to make the result reproducible.
 func keys(m map[*RouteRecord]bool) (ret []*RouteRecord) {
 	ret = make([]*RouteRecord,len(m))
 	i:= 0
@@ -323,26 +1429,141 @@ func keys(m map[*RouteRecord]bool) (ret []*RouteRecord) {
 		ret[i] = rp
 		i++
 	}
+	sort.Slice(ret,func(i,j int) bool { return routeLess(ret[i],ret[j]) })
 	return
 }
 
+// routeLess orders routes by airline id, source airport id and destination airport id.
+// Routes matching in all of them are ordered by their remaining fields.
+func routeLess(a, b *RouteRecord) bool {
+	switch {
+	case a.AirlineId != b.AirlineId:
+		return a.AirlineId < b.AirlineId
+	case a.SourceAirportId != b.SourceAirportId:
+		return a.SourceAirportId < b.SourceAirportId
+	case a.DestAirportId != b.DestAirportId:
+		return a.DestAirportId < b.DestAirportId
+	case a.Airline != b.Airline:
+		return a.Airline < b.Airline
+	case a.Codeshare != b.Codeshare:
+		return !a.Codeshare
+	case a.Stops != b.Stops:
+		return a.Stops < b.Stops
+	}
+	return a.Equipment < b.Equipment
+}
+
 // Airport returns the AirportRecord of the given airport id.
 func (d *Database) Airport(aid int) (*AirportRecord) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
 	return d.AirportsByIdIndex[aid]
 }
 
+// AirportByID returns the AirportRecord of the given airport id like Airport does.
+func (d *Database) AirportByID(aid int) *AirportRecord {
+	return d.Airport(aid)
+}
+
+// AirlineByID returns the AirlineRecord of the given airline id.
+func (d *Database) AirlineByID(aid int) *AirlineRecord {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.AirlinesByIdIndex[aid]
+}
+
+// AirportByIATA returns the AirportRecord of the given IATA code ignoring case.
+func (d *Database) AirportByIATA(code string) (*AirportRecord) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.AirportsByIATA[normalizeCode(code)]
+}
+
+// AirportByICAO returns the AirportRecord of the given ICAO code ignoring case.
+func (d *Database) AirportByICAO(code string) (*AirportRecord) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.AirportsByICAO[normalizeCode(code)]
+}
+
+// AirportsInCountry returns all airports of the given country.
+func (d *Database) AirportsInCountry(country string) ([]*AirportRecord) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.AirportsByCountry[country]
+}
+
+// AirportsInCity returns all airports of the given city.
+// City names are not unique, so the airports may be located in different countries.
+func (d *Database) AirportsInCity(city string) ([]*AirportRecord) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.AirportsByCity[city]
+}
+
+// AirportByAnyCode returns the AirportRecord of the given IATA or ICAO code ignoring case.
+// In the rare case that a code is the IATA code of one airport and the ICAO code
+// of another one, the airport with the matching ICAO code is returned.
+func (d *Database) AirportByAnyCode(code string) (*AirportRecord) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.AirportsByAnyCode[normalizeCode(code)]
+}
+
+// FindAirport returns the AirportRecord of the given IATA or ICAO code ignoring case and
+// surrounding white space. Three letter codes are looked up as IATA code and four letter
+// codes as ICAO code. In contrast to AirportByAnyCode the length of the code decides
+// which index is used. If no airport matches, nil is returned.
+func (d *Database) FindAirport(code string) *AirportRecord {
+	code = normalizeCode(strings.TrimSpace(code))
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	switch len(code) {
+	case 3:
+		return d.AirportsByIATA[code]
+	case 4:
+		return d.AirportsByICAO[code]
+	}
+	return nil
+}
+
+// AirlineByIATA returns the AirlineRecord of the given IATA code ignoring case.
+// If several airlines share the code, an active airline is preferred.
+func (d *Database) AirlineByIATA(code string) (*AirlineRecord) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.AirlinesByIATA[normalizeCode(code)]
+}
+
+// AirlineByICAO returns the AirlineRecord of the given ICAO code ignoring case.
+// If several airlines share the code, an active airline is preferred.
+func (d *Database) AirlineByICAO(code string) (*AirlineRecord) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.AirlinesByICAO[normalizeCode(code)]
+}
+
 // RoutesToAirport returns all routes to the given airport id.
+// The routes are sorted by airline id, source and destination airport id.
 func (d *Database) RoutesToAirport(aid int) ([]*RouteRecord) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
 	return keys(d.AirportsByIdIndex[aid].DestRoutes)
 }
 
 // RoutesFromAirport returns all routes from the given airport id.
+// The routes are sorted by airline id, source and destination airport id.
 func (d *Database) RoutesFromAirport(aid int) ([]*RouteRecord) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
 	return keys(d.AirportsByIdIndex[aid].SourceRoutes)
 }
 
 // RoutesByAirport returns all routes from or to the given airport id.
+// The routes are sorted by airline id, source and destination airport id.
 func (d *Database) RoutesByAirport(aid int) ([]*RouteRecord) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
 	result := make(map[*RouteRecord]bool)
 	ap := d.AirportsByIdIndex[aid]
 	for rp,_ := range ap.DestRoutes {
@@ -356,3 +1577,403 @@ func (d *Database) RoutesByAirport(aid int) ([]*RouteRecord) {
 	return keys(result)
 }
 
+// RoutesBetween returns all direct routes from the source to the destination airport id
+// regardless of the airline. Only the smaller of the route sets of both airports is scanned.
+// If one of the airports is unknown, nil is returned.
+func (d *Database) RoutesBetween(srcId, dstId int) (ret []*RouteRecord) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	src,dst := d.AirportsByIdIndex[srcId],d.AirportsByIdIndex[dstId]
+	if src == nil || dst == nil {
+		return
+	}
+	set := src.SourceRoutes
+	if len(dst.DestRoutes) < len(set) {
+		set = dst.DestRoutes
+	}
+	for _,r := range keys(set) {
+		if r.SourceAirportP == src && r.DestAirportP == dst {
+			ret = append(ret,r)
+		}
+	}
+	return
+}
+
+// HasDirectRoute reports whether there is any direct route from the source to the
+// destination airport id. Like RoutesBetween it only scans the smaller route set but
+// returns on the first match without allocating.
+func (d *Database) HasDirectRoute(srcId, dstId int) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	src,dst := d.AirportsByIdIndex[srcId],d.AirportsByIdIndex[dstId]
+	if src == nil || dst == nil {
+		return false
+	}
+	set := src.SourceRoutes
+	if len(dst.DestRoutes) < len(set) {
+		set = dst.DestRoutes
+	}
+	for r := range set {
+		if r.SourceAirportP == src && r.DestAirportP == dst {
+			return true
+		}
+	}
+	return false
+}
+
+// OperatingRoutes returns all routes that are not codeshares.
+func (d *Database) OperatingRoutes() (ret []*RouteRecord) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for i := range d.Routes {
+		if !d.Routes[i].Codeshare {
+			ret = append(ret,&d.Routes[i])
+		}
+	}
+	return
+}
+
+// OperatingRoutesByAirport returns all routes from or to the given airport id that are not codeshares.
+func (d *Database) OperatingRoutesByAirport(aid int) (ret []*RouteRecord) {
+	for _,r := range d.RoutesByAirport(aid) {
+		if !r.Codeshare {
+			ret = append(ret,r)
+		}
+	}
+	return
+}
+
+// RemoveRoutes removes all routes matching the given predicate from the database
+// including the route sets of their airlines and airports. It returns the number
+// of removed routes. The remaining route records are moved, so previously obtained
+// RouteRecord pointers become stale.
+// The predicate is called while the database is locked and must not call any of its methods.
+func (d *Database) RemoveRoutes(pred func(*RouteRecord) bool) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := 0
+	for i := range d.Routes {
+		if !pred(&d.Routes[i]) {
+			d.Routes[n] = d.Routes[i]
+			n++
+		}
+	}
+	removed := len(d.Routes) - n
+	if removed > 0 {
+		for i := n; i < len(d.Routes); i++ {
+			d.Routes[i] = RouteRecord{}
+		}
+		d.Routes = d.Routes[:n]
+		d.relinkRoutes()
+	}
+	return removed
+}
+
+// RemoveRoute removes the given route from the database including the route sets of its
+// airline and airports. The route must be a pointer into Routes as returned by the lookup
+// methods. It returns whether the route has been found.
+// Instead of moving all following routes, the last route of Routes is moved into the slot
+// of the removed one, so the order of Routes changes and a pointer to the previously last
+// route becomes stale. All other route records stay in place. Use RemoveRoutes with a
+// predicate to remove several routes at once.
+func (d *Database) RemoveRoute(r *RouteRecord) bool {
+	if r == nil {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	i := d.routeIndex(r)
+	if i < 0 {
+		return false
+	}
+	d.unlinkRoute(r)
+	last := len(d.Routes) - 1
+	if i != last {
+		d.relocateRoute(&d.Routes[last],r)
+	}
+	d.Routes[last] = RouteRecord{}
+	d.Routes = d.Routes[:last]
+	return true
+}
+
+// routeIndex returns the index of the given route in Routes or -1 if it does not point into Routes.
+func (d *Database) routeIndex(r *RouteRecord) int {
+	for i := range d.Routes {
+		if &d.Routes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// unlinkRoute removes the given route from the route sets of its airline and airports and
+// from the route key index. If the route is indexed by its key, the other route with the
+// same key that has been loaded or added first is indexed instead.
+func (d *Database) unlinkRoute(r *RouteRecord) {
+	if r.AirlineP != nil {
+		delete(r.AirlineP.Routes,r)
+	}
+	if r.SourceAirportP != nil {
+		delete(r.SourceAirportP.SourceRoutes,r)
+	}
+	if r.DestAirportP != nil {
+		delete(r.DestAirportP.DestRoutes,r)
+	}
+	key := routeKey{r.AirlineId,r.SourceAirportId,r.DestAirportId}
+	if d.routesByKey[key] != r {
+		return
+	}
+	delete(d.routesByKey,key)
+	// Duplicates share the airline, so only its routes need to be scanned.
+	var first *RouteRecord
+	consider := func(c *RouteRecord) {
+		if c != r && (first == nil || c.seq < first.seq) &&
+			(routeKey{c.AirlineId,c.SourceAirportId,c.DestAirportId}) == key {
+			first = c
+		}
+	}
+	if r.AirlineP != nil {
+		for c := range r.AirlineP.Routes {
+			consider(c)
+		}
+	} else if r.SourceAirportP != nil {
+		for c := range r.SourceAirportP.SourceRoutes {
+			consider(c)
+		}
+	} else {
+		for i := range d.Routes {
+			consider(&d.Routes[i])
+		}
+	}
+	if first != nil {
+		d.routesByKey[key] = first
+	}
+}
+
+// relocateRoute moves the route from one slot of Routes to another and updates the route
+// sets of its airline and airports and the route key index.
+func (d *Database) relocateRoute(from, to *RouteRecord) {
+	*to = *from
+	if to.AirlineP != nil {
+		delete(to.AirlineP.Routes,from)
+		to.AirlineP.Routes[to] = true
+	}
+	if to.SourceAirportP != nil {
+		delete(to.SourceAirportP.SourceRoutes,from)
+		to.SourceAirportP.SourceRoutes[to] = true
+	}
+	if to.DestAirportP != nil {
+		delete(to.DestAirportP.DestRoutes,from)
+		to.DestAirportP.DestRoutes[to] = true
+	}
+	if key := (routeKey{to.AirlineId,to.SourceAirportId,to.DestAirportId}); d.routesByKey[key] == from {
+		d.routesByKey[key] = to
+	}
+}
+
+// CompactRoutes reduces the memory used by the routes. The airport and airline codes of
+// all routes with resolved references are dropped as they are redundant to the codes of the
+// referenced records. Use SourceCode, DestCode and AirlineCode to obtain the codes of a
+// compacted route. Identical equipment strings are shared and detached from the csv lines
+// they have been read from, so the lines can be garbage collected.
+func (d *Database) CompactRoutes() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	equipment := make(map[string]string)
+	for i := range d.Routes {
+		r := &d.Routes[i]
+		if r.SourceAirportP != nil {
+			r.SourceAirport = ""
+		}
+		if r.DestAirportP != nil {
+			r.DestAirport = ""
+		}
+		if r.AirlineP != nil {
+			r.Airline = ""
+		}
+		e,ok := equipment[r.Equipment]
+		if !ok {
+			e = strings.Clone(r.Equipment)
+			equipment[e] = e
+		}
+		r.Equipment = e
+	}
+}
+
+// FindRoute returns the first route in the order of Routes matching the given predicate
+// or nil if no route matches. The scan stops at the first match.
+// The predicate is called while the database is locked and must not call any of its methods.
+func (d *Database) FindRoute(pred func(*RouteRecord) bool) *RouteRecord {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for i := range d.Routes {
+		if pred(&d.Routes[i]) {
+			return &d.Routes[i]
+		}
+	}
+	return nil
+}
+
+// FilterRoutes returns all routes matching the given predicate in the order of Routes.
+// The predicate is called while the database is locked and must not call any of its methods.
+func (d *Database) FilterRoutes(pred func(*RouteRecord) bool) (ret []*RouteRecord) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for i := range d.Routes {
+		if pred(&d.Routes[i]) {
+			ret = append(ret,&d.Routes[i])
+		}
+	}
+	return
+}
+
+// Route returns the route of the given airline from the source to the destination airport
+// id or nil if there is no such route. If there are several routes with these ids, the first
+// of them in the order they have been loaded or added is returned. This is the first of them
+// in the order of Routes unless RemoveRoute has reordered Routes. The route can be modified
+// in place to update attributes like Stops or Equipment, but not the ids.
+func (d *Database) Route(airlineId, srcId, dstId int) *RouteRecord {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.routesByKey[routeKey{airlineId,srcId,dstId}]
+}
+
+// RoutesByEquipment returns all routes operated with the given aircraft type code like
+// "744" in the order of Routes. The code is compared to each of the codes of the
+// Equipment field ignoring case.
+func (d *Database) RoutesByEquipment(code string) []*RouteRecord {
+	return d.FilterRoutes(func(r *RouteRecord) bool {
+		for _,c := range r.EquipmentCodes() {
+			if strings.EqualFold(c,code) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// AllRoutes returns pointers to all routes of the database.
+// The pointers refer to the records in Routes.
+func (d *Database) AllRoutes() (ret []*RouteRecord) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	ret = make([]*RouteRecord,len(d.Routes))
+	for i := range d.Routes {
+		ret[i] = &d.Routes[i]
+	}
+	return
+}
+
+// RoutesByAirline returns all routes of the given airline id.
+// The routes are sorted by source and destination airport id.
+func (d *Database) RoutesByAirline(aid int) ([]*RouteRecord) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	al := d.AirlinesByIdIndex[aid]
+	if al == nil {
+		return nil
+	}
+	return keys(al.Routes)
+}
+
+// ActiveAirlines returns all airlines that are flagged as active in the order of the source file.
+func (d *Database) ActiveAirlines() (ret []*AirlineRecord) {
+	for i := range d.Airlines {
+		if d.Airlines[i].Active {
+			ret = append(ret,&d.Airlines[i])
+		}
+	}
+	return
+}
+
+// OperatingAirlines returns all distinct airlines that operate at least one route.
+// The airlines are ordered by their first appearance in the route data.
+func (d *Database) OperatingAirlines() (ret []*AirlineRecord) {
+	seen := make(map[*AirlineRecord]bool)
+	for i := range d.Routes {
+		al := d.Routes[i].AirlineP
+		if al != nil && !seen[al] {
+			seen[al] = true
+			ret = append(ret,al)
+		}
+	}
+	return
+}
+
+// RoutesBetweenCountries returns all routes from an airport in srcCountry to an
+// airport in dstCountry.
+func (d *Database) RoutesBetweenCountries(srcCountry, dstCountry string) []*RouteRecord {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.routesBetweenCountries(srcCountry,dstCountry)
+}
+
+// routesBetweenCountries returns all routes from srcCountry to dstCountry.
+// The caller must hold the read lock.
+func (d *Database) routesBetweenCountries(srcCountry, dstCountry string) (ret []*RouteRecord) {
+	for i := range d.Routes {
+		r := &d.Routes[i]
+		if r.SourceAirportP != nil && r.DestAirportP != nil &&
+			r.SourceAirportP.Country == srcCountry && r.DestAirportP.Country == dstCountry {
+			ret = append(ret,r)
+		}
+	}
+	return
+}
+
+// AirlinesBetweenCountries returns all distinct airlines operating a route from
+// srcCountry to dstCountry.
+func (d *Database) AirlinesBetweenCountries(srcCountry, dstCountry string) (ret []*AirlineRecord) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	seen := make(map[*AirlineRecord]bool)
+	for _,r := range d.routesBetweenCountries(srcCountry,dstCountry) {
+		if al := r.AirlineP; al != nil && !seen[al] {
+			seen[al] = true
+			ret = append(ret,al)
+		}
+	}
+	return
+}
+
+// DestinationsFrom returns all distinct airports that can be reached from the given
+// airport id with a single leg.
+func (d *Database) DestinationsFrom(airportId int) (ret []*AirportRecord) {
+	ap := d.AirportsByIdIndex[airportId]
+	if ap == nil {
+		return
+	}
+	seen := make(map[int]bool)
+	for _,r := range keys(ap.SourceRoutes) {
+		if dst := r.DestAirportP; dst != nil && !seen[dst.Id] {
+			seen[dst.Id] = true
+			ret = append(ret,dst)
+		}
+	}
+	return
+}
+
+// SearchAirports returns all airports whose name, city or IATA code contains the given
+// query ignoring case. Airports with a matching IATA code come first, followed by
+// airports whose name starts with the query and all other matches.
+// An empty query results in an empty slice.
+func (d *Database) SearchAirports(query string) []*AirportRecord {
+	q := strings.ToLower(strings.TrimSpace(query))
+	if q == "" {
+		return []*AirportRecord{}
+	}
+	var tiers [3][]*AirportRecord
+	for i := range d.Airports {
+		a := &d.Airports[i]
+		name := strings.ToLower(a.Name)
+		switch {
+		case strings.ToLower(a.IATA) == q:
+			tiers[0] = append(tiers[0],a)
+		case strings.HasPrefix(name,q):
+			tiers[1] = append(tiers[1],a)
+		case strings.Contains(name,q) || strings.Contains(strings.ToLower(a.City),q) || strings.Contains(strings.ToLower(a.IATA),q):
+			tiers[2] = append(tiers[2],a)
+		}
+	}
+	return append(append(append([]*AirportRecord{},tiers[0]...),tiers[1]...),tiers[2]...)
+}
@@ -1,7 +1,19 @@
 package gopenflights
 
 import(
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 var db *Database
@@ -39,3 +51,1495 @@ func TestRoutesByAirport( t *testing.T) {
 	t.Logf("AirportId[%d] Incoming: %d, Outgoing: %d, Total: %d",jfk,lto,lfrom,lall)
 }
 
+
+// loadTestDatabase loads the small offline data set found in testdata.
+func loadTestDatabase() *Database {
+	return NewDatabase("testdata/airports.dat","testdata/routes.dat","testdata/airlines.dat")
+}
+
+type codeRecord struct {
+	Code string
+}
+
+func (r *codeRecord) Convert(s []string) error {
+	if len(s) < 5 {
+		return fmt.Errorf("Invalid field count for code record: %d/%d",len(s),5)
+	}
+	r.Code = s[4]
+	return nil
+}
+
+func TestLoadRecords(t *testing.T) {
+	tdb := loadTestDatabase()
+	var codes []string
+	tdb.LoadRecords("testdata/airports.dat",
+		func() Record { return new(codeRecord) },
+		func(r Record) { codes = append(codes,r.(*codeRecord).Code) })
+
+	if len(codes) != len(tdb.Airports) {
+		t.Errorf("Expected %d records but got %d",len(tdb.Airports),len(codes))
+	}
+	if codes[1] != "FRA" {
+		t.Errorf("Unexpected code of second record: %s",codes[1])
+	}
+}
+
+func TestLoadRouteDataSkipsInvalid(t *testing.T) {
+	tdb := loadTestDatabase()
+	if l := len(tdb.Routes); l != 20 {
+		t.Errorf("Expected 20 routes but got %d",l)
+	}
+	for i := range tdb.Routes {
+		if tdb.Routes[i].SourceAirportId == 0 || tdb.Routes[i].DestAirportId == 0 {
+			t.Errorf("Route %d has not been skipped: %v",i,tdb.Routes[i])
+		}
+	}
+}
+
+func TestLookupByID(t *testing.T) {
+	tdb := loadTestDatabase()
+	if a := tdb.AirportByID(507); a == nil || a.IATA != "LHR" {
+		t.Errorf("Expected LHR for airportId 507.")
+	}
+	if al := tdb.AirlineByID(1355); al == nil || al.IATA != "BA" {
+		t.Errorf("Expected British Airways for airlineId 1355.")
+	}
+	if tdb.AirportByID(42) != nil || tdb.AirlineByID(9999) != nil {
+		t.Errorf("Expected nil for unknown ids.")
+	}
+}
+
+func TestCodeCase(t *testing.T) {
+	tdb := loadTestDatabase()
+	if a := tdb.AirportByIATA("jfk"); a == nil || a.Id != 3797 {
+		t.Errorf("Expected JFK for a lower case IATA code.")
+	}
+	if a := tdb.AirportByICAO("kJfK"); a == nil || a.Id != 3797 {
+		t.Errorf("Expected JFK for a mixed case ICAO code.")
+	}
+	if a := tdb.AirportByAnyCode("eglL"); a == nil || a.Id != 507 {
+		t.Errorf("Expected LHR for a mixed case code.")
+	}
+	if al := tdb.AirlineByIATA("lh"); al == nil || al.Id != 3090 {
+		t.Errorf("Expected Lufthansa for a lower case IATA code.")
+	}
+	if al := tdb.AirlineByICAO("baw"); al == nil || al.Id != 1355 {
+		t.Errorf("Expected British Airways for a lower case ICAO code.")
+	}
+
+	d := &Database{}
+	d.setAirportData("test",[][]string{{"1","Airport","City","Country","abc","wxyz","1","1","0","0","E"}})
+	if d.AirportsByIATA["ABC"] == nil || d.AirportsByICAO["WXYZ"] == nil || d.AirportByAnyCode("abc") == nil {
+		t.Errorf("Expected lower case codes to be indexed in upper case.")
+	}
+	if d.Airports[0].IATA != "abc" {
+		t.Errorf("Records must keep their original codes.")
+	}
+}
+
+func TestFindAirport(t *testing.T) {
+	tdb := loadTestDatabase()
+	for _,code := range []string{"LHR","lhr"," EGLL ","egll"} {
+		if a := tdb.FindAirport(code); a == nil || a.Id != 507 {
+			t.Errorf("Expected LHR for \"%s\" but got %v",code,a)
+		}
+	}
+	for _,code := range []string{"","XX","XYZ","EGL","EGLLX"} {
+		if a := tdb.FindAirport(code); a != nil {
+			t.Errorf("Expected no airport for \"%s\" but got %s",code,a.Name)
+		}
+	}
+}
+
+func TestRoutesBetween(t *testing.T) {
+	tdb := loadTestDatabase()
+	// AA (codeshare) and BA fly LHR -> JFK.
+	if rs := tdb.RoutesBetween(507,3797); len(rs) != 2 {
+		t.Errorf("Expected 2 routes from LHR to JFK but got %d",len(rs))
+	}
+	for _,r := range tdb.RoutesBetween(3797,507) {
+		if r.SourceAirport != "JFK" || r.DestAirport != "LHR" {
+			t.Errorf("Unexpected route %s -> %s",r.SourceAirport,r.DestAirport)
+		}
+	}
+	if rs := tdb.RoutesBetween(1,507); len(rs) != 0 {
+		t.Errorf("Expected no routes from GKA but got %d",len(rs))
+	}
+	if rs := tdb.RoutesBetween(507,42); rs != nil {
+		t.Errorf("Expected nil for an unknown airport.")
+	}
+}
+
+func TestHasDirectRoute(t *testing.T) {
+	tdb := loadTestDatabase()
+	if !tdb.HasDirectRoute(507,3797) || !tdb.HasDirectRoute(3797,507) {
+		t.Errorf("Expected direct routes between LHR and JFK.")
+	}
+	// There is only a route from LHR to DUS but not back.
+	if !tdb.HasDirectRoute(507,345) || tdb.HasDirectRoute(345,507) {
+		t.Errorf("Unexpected direct routes between LHR and DUS.")
+	}
+	if tdb.HasDirectRoute(1,507) || tdb.HasDirectRoute(507,42) {
+		t.Errorf("Expected no direct routes from GKA or to unknown airports.")
+	}
+}
+
+func TestOperatingAirlines(t *testing.T) {
+	tdb := loadTestDatabase()
+	ops := tdb.OperatingAirlines()
+	if len(ops) != 6 {
+		t.Errorf("Expected 6 operating airlines but got %d",len(ops))
+	}
+	for _,al := range ops {
+		if al.Id == 2 || al.Id == -1 {
+			t.Errorf("Airline %s does not operate any route",al.Name)
+		}
+	}
+}
+
+func TestDuplicateIATACodes(t *testing.T) {
+	tdb := loadTestDatabase()
+	if dups := tdb.DuplicateIATACodes(); len(dups) != 0 {
+		t.Errorf("Expected no duplicate IATA codes but got %v",dups)
+	}
+
+	d := &Database{}
+	d.setAirportData("test",[][]string{
+		{"1","First","City","Country","XYZ","\\N","1","1","0","0","E"},
+		{"2","Second","City","Country","XYZ","\\N","2","2","0","0","E"},
+		{"3","Other","City","Country","ABC","\\N","3","3","0","0","E"},
+		{"4","Third","City","Country","XYZ","\\N","4","4","0","0","E"},
+	})
+	dups := d.DuplicateIATACodes()
+	if ids := dups["XYZ"]; len(dups) != 1 || len(ids) != 3 || ids[0] != 1 || ids[1] != 2 || ids[2] != 4 {
+		t.Errorf("Unexpected duplicate IATA codes: %v",dups)
+	}
+	if len(d.LoadWarnings()) != 2 {
+		t.Errorf("Expected 2 warnings but got %v",d.LoadWarnings())
+	}
+}
+
+func TestActiveAirlines(t *testing.T) {
+	tdb := loadTestDatabase()
+	act := tdb.ActiveAirlines()
+	if len(act) != 7 {
+		t.Errorf("Expected 7 active airlines but got %d",len(act))
+	}
+	for _,al := range act {
+		if !al.Active {
+			t.Errorf("Airline %s is not active",al.Name)
+		}
+	}
+
+	d := &Database{}
+	d.setAirlineData("test",[][]string{
+		{"1","Active Air","\\N","OA","OLD","OLD","Germany","Y"},
+		{"2","Defunct Air","\\N","OA","NEW","NEW","Germany","N"},
+		{"3","Defunct Cargo","\\N","OB","OLD","OTHER","Germany","N"},
+	})
+	if al := d.AirlineByIATA("OA"); al == nil || al.Id != 1 {
+		t.Errorf("Expected the active airline for a shared IATA code but got %v",al)
+	}
+	if al := d.AirlineByICAO("OLD"); al == nil || al.Id != 1 {
+		t.Errorf("Expected the active airline for a shared ICAO code but got %v",al)
+	}
+}
+
+// testLogger collects all logged messages.
+type testLogger []string
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	*l = append(*l,fmt.Sprintf(format,v...))
+}
+
+func TestLogger(t *testing.T) {
+	var logged testLogger
+	tdb,err := Open(WithAirportsFile("testdata/airports.dat"),WithRoutesFile("testdata/routes.dat"),WithAirlinesFile("testdata/airlines.dat"),WithLogger(&logged))
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnings := tdb.LoadWarnings()
+	// 3 loading messages followed by the warnings.
+	if len(warnings) == 0 || len(logged) != len(warnings) + 3 {
+		t.Errorf("Expected all warnings to be logged: %v/%v",warnings,logged)
+	}
+
+	tdb.SetLogger(NopLogger)
+	if err = tdb.LoadRouteData("testdata/routes.dat"); err != nil {
+		t.Fatal(err)
+	}
+	if len(logged) != len(warnings) + 3 {
+		t.Errorf("Expected no further messages but got %v",logged[len(warnings) + 3:])
+	}
+	if len(tdb.LoadWarnings()) == 0 {
+		t.Errorf("Expected warnings to be collected without logging.")
+	}
+}
+
+func TestLoadWarningsOnReload(t *testing.T) {
+	tdb := loadTestDatabase()
+	warnings := tdb.LoadWarnings()
+	if len(warnings) == 0 {
+		t.Fatalf("Expected warnings for the invalid routes.")
+	}
+	tdb.SetLogger(NopLogger)
+	if err := tdb.LoadRouteData("testdata/routes.dat"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tdb.LoadAirportsFrom(strings.NewReader("1,Goroka,Goroka,Papua New Guinea,GKA,AYGA,-6.08,145.39,5282,10,U\n")); err != nil {
+		t.Fatal(err)
+	}
+	tdb.Reindex()
+	if !reflect.DeepEqual(tdb.LoadWarnings(),warnings) {
+		t.Errorf("Expected the warnings of reloaded files to be replaced: %v",tdb.LoadWarnings())
+	}
+
+	// The warnings of a file are replaced by the ones of its new data.
+	if err := tdb.LoadRoutesFrom(strings.NewReader("LH,3090,FRA,340,XYZ,\\N,,0,320\nLH,3090,FRA,340,XYZ,\\N,,0,320\n")); err != nil {
+		t.Fatal(err)
+	}
+	if w := tdb.LoadWarnings(); len(w) != 2 || !strings.Contains(w[0],"@line 1") || !strings.Contains(w[1],"@line 2") {
+		t.Errorf("Unexpected warnings of the new routes: %v",w)
+	}
+}
+
+func TestNewDatabaseWithReport(t *testing.T) {
+	_,report,err := NewDatabaseWithReport("testdata/airports.dat","testdata/routes.dat","testdata/airlines.dat")
+	if err != nil {
+		t.Fatalf("Could not load database: %s",err)
+	}
+	if report.Routes.Loaded != 20 || report.Routes.Skipped != 1 {
+		t.Errorf("Unexpected route counts: %+v",report.Routes)
+	}
+	if report.Airports.Loaded != 9 || report.Airports.Skipped != 0 {
+		t.Errorf("Unexpected airport counts: %+v",report.Airports)
+	}
+	if len(report.Warnings) == 0 {
+		t.Errorf("Expected warnings for the invalid routes.")
+	}
+
+	if _,_,err = NewDatabaseWithReport("testdata/airports.dat"); err == nil {
+		t.Errorf("Expected an error for an incomplete set of source files.")
+	}
+}
+
+func TestAirportByAnyCode(t *testing.T) {
+	tdb := loadTestDatabase()
+	if a := tdb.AirportByAnyCode("JFK"); a == nil || a.Id != 3797 {
+		t.Errorf("Could not find JFK by IATA code.")
+	}
+	if a := tdb.AirportByAnyCode("EDDL"); a == nil || a.Id != 345 {
+		t.Errorf("Could not find DUS by ICAO code.")
+	}
+	if a := tdb.AirportByAnyCode(""); a != nil {
+		t.Errorf("Empty code must not be indexed but found %s",a.Name)
+	}
+}
+
+func TestContinent(t *testing.T) {
+	tdb := loadTestDatabase()
+	for code,continent := range map[string]string{"JFK": NorthAmerica, "DUS": Europe, "HND": Asia, "SYD": Oceania, "GKA": Oceania} {
+		if c := tdb.AirportsByIATA[code].Continent(); c != continent {
+			t.Errorf("Expected %s to be in %s but got %s",code,continent,c)
+		}
+	}
+	if c := (&AirportRecord{Country: "Atlantis"}).Continent(); c != UnknownContinent {
+		t.Errorf("Expected an unknown continent but got %s",c)
+	}
+}
+
+func TestAirportsByContinent(t *testing.T) {
+	tdb := loadTestDatabase()
+	byContinent := tdb.AirportsByContinent()
+	if len(byContinent[Europe]) != 4 || len(byContinent[NorthAmerica]) != 2 || len(byContinent[Oceania]) != 2 || len(byContinent[Asia]) != 1 {
+		t.Errorf("Unexpected airports per continent: %v",byContinent)
+	}
+	if _,ok := byContinent[UnknownContinent]; ok {
+		t.Errorf("All test airports are located in known countries.")
+	}
+	tdb.Airports[0].Country = "Atlantis"
+	if u := tdb.AirportsByContinent()[UnknownContinent]; len(u) != 1 || u[0].Id != 1 {
+		t.Errorf("Expected GKA to be grouped under %s.",UnknownContinent)
+	}
+}
+
+func TestIsIntercontinental(t *testing.T) {
+	tdb := loadTestDatabase()
+	for i := range tdb.Routes {
+		r := &tdb.Routes[i]
+		ic,err := r.IsIntercontinental(tdb)
+		switch {
+		case r.DestAirportId == 99999:
+			if err == nil {
+				t.Errorf("Expected an error for unresolved route %s -> %s",r.SourceAirport,r.DestAirport)
+			}
+		case err != nil:
+			t.Errorf("Unexpected error: %s",err)
+		case r.SourceAirport == "FRA" && r.DestAirport == "DUS" && ic:
+			t.Errorf("FRA -> DUS is not intercontinental.")
+		case r.SourceAirport == "FRA" && r.DestAirport == "JFK" && !ic:
+			t.Errorf("FRA -> JFK is intercontinental.")
+		}
+	}
+}
+
+func TestFindAndFilterRoutes(t *testing.T) {
+	tdb := loadTestDatabase()
+	calls := 0
+	r := tdb.FindRoute(func(r *RouteRecord) bool {
+		calls++
+		return r.Codeshare
+	})
+	if r == nil || r.AirlineId != 24 || r.SourceAirport != "LHR" {
+		t.Errorf("Expected the AA codeshare from LHR but got %v",r)
+	}
+	if calls == len(tdb.Routes) {
+		t.Errorf("Expected the scan to stop at the first match.")
+	}
+	if r = tdb.FindRoute(func(r *RouteRecord) bool { return r.Stops > 0 }); r != nil {
+		t.Errorf("Expected no route with stops.")
+	}
+
+	qf := tdb.FilterRoutes(func(r *RouteRecord) bool { return r.AirlineId == 4296 })
+	if len(qf) != 2 || qf[0].SourceAirport != "SYD" || qf[1].SourceAirport != "LAX" {
+		t.Errorf("Unexpected Qantas routes: %v",qf)
+	}
+	if rs := tdb.FilterRoutes(func(*RouteRecord) bool { return true }); len(rs) != len(tdb.Routes) {
+		t.Errorf("Expected all routes but got %d",len(rs))
+	}
+}
+
+func TestAllRoutes(t *testing.T) {
+	tdb := loadTestDatabase()
+	all := tdb.AllRoutes()
+	if len(all) != len(tdb.Routes) {
+		t.Fatalf("Expected %d routes but got %d",len(tdb.Routes),len(all))
+	}
+	if !tdb.Airport(all[0].SourceAirportId).SourceRoutes[all[0]] {
+		t.Errorf("Route pointer is not the one registered at its source airport.")
+	}
+}
+
+func TestAirlinesBetweenCountries(t *testing.T) {
+	tdb := loadTestDatabase()
+	als := tdb.AirlinesBetweenCountries("Germany","United States")
+	if len(als) != 2 {
+		t.Errorf("Expected Air Berlin and Lufthansa but got %d airlines.",len(als))
+	}
+	if als := tdb.AirlinesBetweenCountries("Germany","Japan"); len(als) != 1 || als[0].IATA != "LH" {
+		t.Errorf("Expected only Lufthansa to fly from Germany to Japan.")
+	}
+}
+
+func TestAddRoute(t *testing.T) {
+	tdb := loadTestDatabase()
+	count := len(tdb.RoutesFromAirport(3484))
+	// Add enough routes to force the route slice to grow.
+	for i := 0; i < 50; i++ {
+		err := tdb.AddRoute(RouteRecord{Airline: "AB", AirlineId: 214, SourceAirport: "LAX", SourceAirportId: 3484, DestAirport: "DUS", DestAirportId: 345})
+		if err != nil {
+			t.Fatalf("Could not add route: %s",err)
+		}
+	}
+	if l := len(tdb.RoutesFromAirport(3484)); l != count + 50 {
+		t.Errorf("Expected %d routes from LAX but got %d",count + 50,l)
+	}
+	for _,r := range tdb.RoutesByAirline(214) {
+		if !tdb.Airport(r.SourceAirportId).SourceRoutes[r] {
+			t.Errorf("Route of airline is not registered at its source airport.")
+		}
+	}
+	if err := tdb.AddRoute(RouteRecord{SourceAirportId: 3484}); err == nil {
+		t.Errorf("Expected an error for a route without destination.")
+	}
+	total := len(tdb.Routes)
+	if err := tdb.AddRoute(RouteRecord{SourceAirportId: 3484, DestAirportId: 99999}); err == nil {
+		t.Errorf("Expected an error for an unknown destination airport.")
+	}
+	if err := tdb.AddRoute(RouteRecord{SourceAirportId: 99999, DestAirportId: 3484}); err == nil {
+		t.Errorf("Expected an error for an unknown source airport.")
+	}
+	if len(tdb.Routes) != total {
+		t.Errorf("Routes with unknown airports must not be added.")
+	}
+}
+
+func TestRemoveRoute(t *testing.T) {
+	tdb := loadTestDatabase()
+	total := len(tdb.Routes)
+	rs := tdb.RoutesBetween(507,3797)
+	if len(rs) != 2 || !tdb.RemoveRoute(rs[0]) {
+		t.Fatalf("Expected a route from LHR to JFK to be removed.")
+	}
+	if len(tdb.Routes) != total - 1 || len(tdb.RoutesBetween(507,3797)) != 1 {
+		t.Errorf("Expected one route from LHR to JFK to remain.")
+	}
+	lhr,jfk := tdb.Airport(507),tdb.Airport(3797)
+	for _,r := range tdb.AllRoutes() {
+		if r.SourceAirportP == lhr && !lhr.SourceRoutes[r] || r.DestAirportP == jfk && !jfk.DestRoutes[r] {
+			t.Errorf("Route %s -> %s is not registered at its airports.",r.SourceAirport,r.DestAirport)
+		}
+	}
+	if len(lhr.SourceRoutes) != len(tdb.RoutesFromAirport(507)) {
+		t.Errorf("Route sets of LHR are inconsistent.")
+	}
+	if tdb.RemoveRoute(&RouteRecord{SourceAirportId: 507, DestAirportId: 3797}) || tdb.RemoveRoute(nil) {
+		t.Errorf("Routes not contained in the database must not be found.")
+	}
+	if len(tdb.Routes) != total - 1 {
+		t.Errorf("Expected %d routes but got %d",total - 1,len(tdb.Routes))
+	}
+}
+
+func TestRemoveRoutesOfLookup(t *testing.T) {
+	tdb := loadTestDatabase()
+	total := len(tdb.Routes)
+	// The last route of Routes belongs to Lufthansa, so the routes of AA stay in place.
+	for _,r := range tdb.RoutesByAirline(24) {
+		if r.AirlineId != 24 || !tdb.RemoveRoute(r) {
+			t.Fatalf("Could not remove route %s -> %s of AA",r.SourceCode(),r.DestCode())
+		}
+	}
+	if n := tdb.RemoveRoutes(func(r *RouteRecord) bool { return r.AirlineId == 3090 }); n != 6 {
+		t.Errorf("Expected 6 removed routes of LH but got %d",n)
+	}
+	if len(tdb.Routes) != total - 10 {
+		t.Errorf("Expected %d routes but got %d",total - 10,len(tdb.Routes))
+	}
+	if zz := tdb.Route(9999,507,345); zz == nil || zz.Airline != "ZZ" {
+		t.Errorf("Expected the unrelated route of ZZ to remain.")
+	}
+	for i := range tdb.Routes {
+		r := &tdb.Routes[i]
+		if r.AirlineId == 24 || r.AirlineId == 3090 {
+			t.Errorf("Route %s -> %s of airline %d has not been removed.",r.SourceCode(),r.DestCode(),r.AirlineId)
+		}
+		if r.SourceAirportP != nil && !r.SourceAirportP.SourceRoutes[r] || r.DestAirportP != nil && !r.DestAirportP.DestRoutes[r] ||
+			r.AirlineP != nil && !r.AirlineP.Routes[r] || tdb.Route(r.AirlineId,r.SourceAirportId,r.DestAirportId) != r {
+			t.Errorf("Route %s -> %s is not linked.",r.SourceCode(),r.DestCode())
+		}
+	}
+	for i := range tdb.Airports {
+		for r := range tdb.Airports[i].SourceRoutes {
+			if tdb.routeIndex(r) < 0 {
+				t.Errorf("Airport %s refers to a removed route.",tdb.Airports[i].IATA)
+			}
+		}
+	}
+	if len(tdb.RoutesByAirline(24)) != 0 || len(tdb.RoutesByAirline(3090)) != 0 {
+		t.Errorf("Expected no routes of AA and LH.")
+	}
+
+	// Routes can be added again after removal without moving the route records.
+	first := &tdb.Routes[0]
+	if err := tdb.AddRoute(RouteRecord{Airline: "LH", AirlineId: 3090, SourceAirportId: 340, DestAirportId: 345}); err != nil {
+		t.Fatal(err)
+	}
+	if r := tdb.Route(3090,340,345); r == nil || !r.SourceAirportP.SourceRoutes[r] {
+		t.Errorf("Expected the added route to be linked.")
+	}
+	if first != &tdb.Routes[0] {
+		t.Errorf("Expected the route records to stay in place.")
+	}
+}
+
+func TestRemoveRoutes(t *testing.T) {
+	tdb := loadTestDatabase()
+	total := len(tdb.Routes)
+	n := tdb.RemoveRoutes(func(r *RouteRecord) bool { return r.AirlineId == 214 })
+	if n != 2 || len(tdb.Routes) != total - 2 {
+		t.Errorf("Expected 2 removed routes but got %d",n)
+	}
+	if l := len(tdb.RoutesByAirline(214)); l != 0 {
+		t.Errorf("Air Berlin still has %d routes.",l)
+	}
+	for _,r := range tdb.RoutesByAirport(345) {
+		if r.AirlineId == 214 {
+			t.Errorf("Removed route is still registered at DUS.")
+		}
+		if r.DestAirportP != nil && !r.DestAirportP.DestRoutes[r] {
+			t.Errorf("Route is not registered at its destination airport.")
+		}
+	}
+}
+
+func TestEquipmentCodes(t *testing.T) {
+	r := RouteRecord{Equipment: " 320 738  CR2 "}
+	if codes := r.EquipmentCodes(); len(codes) != 3 || codes[0] != "320" || codes[2] != "CR2" {
+		t.Errorf("Unexpected equipment codes: %v",codes)
+	}
+	r.Equipment = ""
+	if codes := r.EquipmentCodes(); codes == nil || len(codes) != 0 {
+		t.Errorf("Expected an empty slice but got %#v",codes)
+	}
+}
+
+func TestAirlineByCode(t *testing.T) {
+	tdb := loadTestDatabase()
+	if al := tdb.AirlineByIATA("AA"); al == nil || al.Name != "American Airlines" {
+		t.Errorf("Could not find American Airlines by IATA code.")
+	}
+	if al := tdb.AirlineByICAO("DLH"); al == nil || al.Name != "Lufthansa" {
+		t.Errorf("Could not find Lufthansa by ICAO code.")
+	}
+	for _,code := range []string{"","-","N/A"} {
+		if tdb.AirlineByIATA(code) != nil || tdb.AirlineByICAO(code) != nil {
+			t.Errorf("Unspecified code \"%s\" must not be indexed.",code)
+		}
+	}
+}
+
+func TestAirportCodeIndexSkipsEmptyCodes(t *testing.T) {
+	tdb := loadTestDatabase()
+	for _,code := range []string{"","\\N"} {
+		if a := tdb.AirportsByIATA[code]; a != nil {
+			t.Errorf("AirportsByIATA[\"%s\"] must be nil but is %s",code,a.Name)
+		}
+		if a := tdb.AirportsByICAO[code]; a != nil {
+			t.Errorf("AirportsByICAO[\"%s\"] must be nil but is %s",code,a.Name)
+		}
+	}
+}
+
+func TestConvertNullFields(t *testing.T) {
+	var ap AirportRecord
+	if err := ap.Convert([]string{"1","\\N","\\N","\\N","\\N","\\N","\\N","\\N","\\N","\\N","\\N"}); err != nil {
+		t.Errorf("Cannot convert airport with null fields: %s",err)
+	}
+	if ap.Name != "" || ap.City != "" || ap.Country != "" || ap.IATA != "" || ap.ICAO != "" || ap.Lat != 0 || ap.DST != 0 {
+		t.Errorf("Null fields of airport are not empty: %+v",ap)
+	}
+
+	var al AirlineRecord
+	if err := al.Convert([]string{"2","\\N","\\N","\\N","\\N","\\N","\\N","\\N"}); err != nil {
+		t.Errorf("Cannot convert airline with null fields: %s",err)
+	}
+	if al.Name != "" || al.Alias != "" || al.IATA != "" || al.ICAO != "" || al.Callsign != "" || al.Country != "" || al.Active {
+		t.Errorf("Null fields of airline are not empty: %+v",al)
+	}
+
+	var r RouteRecord
+	if err := r.Convert([]string{"\\N","\\N","\\N","\\N","\\N","\\N","\\N","\\N","\\N"}); err != nil {
+		t.Errorf("Cannot convert route with null fields: %s",err)
+	}
+	if r.Airline != "" || r.AirlineId != 0 || r.SourceAirport != "" || r.DestAirportId != 0 || r.Equipment != "" || r.Codeshare {
+		t.Errorf("Null fields of route are not empty: %+v",r)
+	}
+}
+
+func TestDSTRule(t *testing.T) {
+	tdb := loadTestDatabase()
+	for code,rule := range map[string]DSTType{"JFK": DSTUSCanada, "DUS": DSTEurope, "SYD": DSTAustralia, "HND": DSTUnknown} {
+		if r := tdb.AirportsByIATA[code].DSTRule(); r != rule {
+			t.Errorf("Expected DST rule %s for %s but got %s",rule,code,r)
+		}
+	}
+	if s := (&AirportRecord{}).DSTRule().String(); s != "Unknown" {
+		t.Errorf("Expected unknown DST rule for missing value but got %s",s)
+	}
+}
+
+func TestAirportLocation(t *testing.T) {
+	tdb := loadTestDatabase()
+	jfk := tdb.AirportsByIATA["JFK"]
+	if jfk.Tz != "America/New_York" {
+		t.Errorf("Unexpected time zone of JFK: %s",jfk.Tz)
+	}
+	loc,err := jfk.Location()
+	if err != nil {
+		t.Skipf("Time zone database not available: %s",err)
+	}
+	if _,off := time.Date(2020,7,1,12,0,0,0,loc).Zone(); off != -4 * 3600 {
+		t.Errorf("Unexpected summer offset of JFK: %d",off)
+	}
+	if _,err = (&AirportRecord{}).Location(); err == nil {
+		t.Errorf("Expected an error for an airport without time zone.")
+	}
+}
+
+func TestAirportSchemaVersions(t *testing.T) {
+	legacy := []string{"3797","John F Kennedy Intl","New York","United States","JFK","KJFK","40.639751","-73.778925","13","-5","A"}
+	modern := append(append([]string{},legacy...),"America/New_York","airport","OurAirports")
+
+	var a AirportRecord
+	if err := a.Convert(modern); err != nil {
+		t.Fatalf("Cannot convert modern airport record: %s",err)
+	}
+	if a.Tz != "America/New_York" || a.Type != "airport" || a.Source != "OurAirports" {
+		t.Errorf("Unexpected modern fields: %s, %s, %s",a.Tz,a.Type,a.Source)
+	}
+
+	if err := a.Convert(legacy); err != nil {
+		t.Fatalf("Cannot convert legacy airport record: %s",err)
+	}
+	if a.IATA != "JFK" || a.Tz != "" || a.Type != "" || a.Source != "" {
+		t.Errorf("Unexpected legacy fields: %s, %s, %s, %s",a.IATA,a.Tz,a.Type,a.Source)
+	}
+
+	tdb := loadTestDatabase()
+	if a := tdb.Airport(8950); a.Type != "station" || a.Source != "User" {
+		t.Errorf("Unexpected type/source of Frankfurt Hbf: %s/%s",a.Type,a.Source)
+	}
+}
+
+func TestDestinationsFrom(t *testing.T) {
+	tdb := loadTestDatabase()
+	// LHR: JFK (AA and BA), FRA and DUS
+	dsts := tdb.DestinationsFrom(507)
+	if len(dsts) != 3 {
+		t.Errorf("Expected 3 destinations from LHR but got %d",len(dsts))
+	}
+	// FRA has a route to an unknown airport.
+	for _,a := range tdb.DestinationsFrom(340) {
+		if a == nil {
+			t.Errorf("Unresolved destinations must be skipped.")
+		}
+	}
+}
+
+func TestOpenWithOptions(t *testing.T) {
+	tdb,err := Open(WithAirportsFile("testdata/airports.dat"),WithAirlinesFile("testdata/airlines.dat"),WithRoutesFile("testdata/routes.dat"))
+	if err != nil {
+		t.Fatalf("Could not open database: %s",err)
+	}
+	if len(tdb.Airports) != 9 || len(tdb.Airlines) != 8 || len(tdb.Routes) != 20 {
+		t.Errorf("Unexpected record counts: %d/%d/%d",len(tdb.Airports),len(tdb.Airlines),len(tdb.Routes))
+	}
+
+	// Only airports are given, the cache directory is used for the remaining sources.
+	dir := t.TempDir()
+	for _,f := range []string{DefaultAirlinesFilename,DefaultRoutesFilename} {
+		data,_ := os.ReadFile("testdata/" + f)
+		os.WriteFile(filepath.Join(dir,f),data,0644)
+	}
+	if tdb,err = Open(WithAirportsFile("testdata/airports.dat"),WithCacheDir(dir)); err != nil {
+		t.Fatalf("Could not open database from cache: %s",err)
+	}
+	if len(tdb.Routes) != 20 {
+		t.Errorf("Expected routes to be loaded from cache directory.")
+	}
+}
+
+func TestLoadDataContextCancel(t *testing.T) {
+	blocked := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(blocked)
+	}))
+	defer srv.Close()
+
+	ctx,cancel := context.WithTimeout(context.Background(),100 * time.Millisecond)
+	defer cancel()
+	tdb := new(Database)
+	if err := tdb.LoadAirportDataContext(ctx,srv.URL + "/airports.dat"); err == nil {
+		t.Errorf("Expected an error for an exceeded deadline.")
+	}
+	<-blocked
+}
+
+// roundTripFunc is a http.RoundTripper used to redirect requests in tests.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestCacheTTLAndRefresh(t *testing.T) {
+	files := map[string]string{
+		DefaultAirportsFilename: "testdata/airports.dat",
+		DefaultAirlinesFilename: "testdata/airlines.dat",
+		DefaultRoutesFilename: "testdata/routes.dat",
+	}
+	downloads := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		http.ServeFile(w,r,files[filepath.Base(r.URL.Path)])
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	for f := range files {
+		data,_ := os.ReadFile(files[f])
+		os.WriteFile(filepath.Join(dir,f),data,0644)
+		old := time.Now().Add(-2 * time.Hour)
+		os.Chtimes(filepath.Join(dir,f),old,old)
+	}
+
+	// Serve the default URLs from the test server.
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return http.Get(srv.URL + "/" + filepath.Base(r.URL.Path))
+	})}
+
+	tdb,err := Open(WithCacheDir(dir),WithCacheTTL(3 * time.Hour),WithHTTPClient(client))
+	if err != nil {
+		t.Fatalf("Could not open database: %s",err)
+	}
+	if downloads != 0 {
+		t.Errorf("Cached files within TTL must not be downloaded.")
+	}
+	if _,err = Open(WithCacheDir(dir),WithCacheTTL(time.Hour),WithHTTPClient(client)); err != nil {
+		t.Fatalf("Could not open database: %s",err)
+	}
+	if downloads != 3 {
+		t.Errorf("Expected 3 downloads for expired cache files but got %d",downloads)
+	}
+	if err = tdb.Refresh(); err != nil {
+		t.Fatalf("Could not refresh database: %s",err)
+	}
+	if downloads != 6 || len(tdb.Routes) != 20 {
+		t.Errorf("Expected 3 downloads and a complete reload on refresh.")
+	}
+}
+
+func TestCacheValidation(t *testing.T) {
+	files := map[string]string{
+		DefaultAirportsFilename: "testdata/airports.dat",
+		DefaultAirlinesFilename: "testdata/airlines.dat",
+		DefaultRoutesFilename: "testdata/routes.dat",
+	}
+	const page = "<!DOCTYPE html><html><body>Service unavailable</body></html>"
+	html := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if html && filepath.Base(r.URL.Path) == DefaultAirportsFilename {
+			w.Write([]byte(page))
+			return
+		}
+		http.ServeFile(w,r,files[filepath.Base(r.URL.Path)])
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return http.Get(srv.URL + "/" + filepath.Base(r.URL.Path))
+	})}
+
+	// A poisoned cache file is downloaded again but an HTML page never replaces it.
+	dir := t.TempDir()
+	cached := filepath.Join(dir,DefaultAirportsFilename)
+	os.WriteFile(cached,[]byte("\n"),0644)
+	if _,err := Open(WithCacheDir(dir),WithHTTPClient(client)); err == nil {
+		t.Errorf("Expected an error for an HTML download.")
+	}
+	if data,_ := os.ReadFile(cached); string(data) != "\n" {
+		t.Errorf("HTML download must not be cached: %s",data)
+	}
+
+	html = false
+	tdb,err := Open(WithCacheDir(dir),WithHTTPClient(client))
+	if err != nil {
+		t.Fatalf("Could not open database: %s",err)
+	}
+	if len(tdb.Airports) != 9 {
+		t.Errorf("Expected the empty cache file to be replaced.")
+	}
+
+	if err = checkDatFile("testdata/routes.dat",11,0); err == nil {
+		t.Errorf("Expected an error for a file with too few columns.")
+	}
+	srv404 := httptest.NewServer(http.NotFoundHandler())
+	defer srv404.Close()
+	if err = DownloadFile(srv404.URL + "/airports.dat",filepath.Join(dir,"404.dat")); err == nil {
+		t.Errorf("Expected an error for a failed request.")
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	srv := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer srv.Close()
+
+	tdb,err := Open(WithCacheDir(t.TempDir()),WithBaseURL(srv.URL + "/"))
+	if err != nil {
+		t.Fatalf("Could not open database: %s",err)
+	}
+	if len(tdb.Airports) != 9 || len(tdb.Routes) != 20 {
+		t.Errorf("Expected the sources to be downloaded from the base URL.")
+	}
+
+	defer func(url string) { DefaultAirportDatUrl = url }(DefaultAirportDatUrl)
+	DefaultAirportDatUrl = srv.URL + "/missing.dat"
+	if _,err = Open(WithCacheDir(t.TempDir()),WithAirlinesFile("testdata/airlines.dat"),WithRoutesFile("testdata/routes.dat")); err == nil {
+		t.Errorf("Expected the overridden default URL to be used.")
+	}
+}
+
+func TestDefaultBaseDatUrl(t *testing.T) {
+	srv := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer srv.Close()
+
+	defer func(url string) { DefaultBaseDatUrl = url }(DefaultBaseDatUrl)
+	DefaultBaseDatUrl = srv.URL
+	tdb,err := Open(WithCacheDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Could not open database: %s",err)
+	}
+	if len(tdb.Airports) != 9 || len(tdb.Airlines) != 8 || len(tdb.Routes) != 20 {
+		t.Errorf("Expected the sources to be downloaded from the changed base URL.")
+	}
+
+	// A changed URL of a single file takes precedence.
+	defer func(url string) { DefaultRoutesDatUrl = url }(DefaultRoutesDatUrl)
+	DefaultRoutesDatUrl = srv.URL + "/missing.dat"
+	if _,err = Open(WithCacheDir(t.TempDir())); err == nil {
+		t.Errorf("Expected the changed routes URL to be used.")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	failures,requests := 0,0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if name := filepath.Base(r.URL.Path); name == DefaultAirportsFilename || name == "missing.dat" {
+			if requests++; requests <= failures {
+				http.Error(w,"unavailable",http.StatusServiceUnavailable)
+				return
+			}
+		}
+		http.ServeFile(w,r,filepath.Join("testdata",filepath.Base(r.URL.Path)))
+	}))
+	defer srv.Close()
+	open := func(ctx context.Context, opts ...Option) (*Database, error) {
+		requests = 0
+		opts = append(opts,WithCacheDir(t.TempDir()),WithBaseURL(srv.URL + "/"),WithLogger(NopLogger))
+		return OpenContext(ctx,opts...)
+	}
+
+	failures = 2
+	if _,err := open(context.Background()); err == nil || requests != 1 {
+		t.Errorf("Expected a single failed attempt without retries but got %d",requests)
+	}
+	tdb,err := open(context.Background(),WithRetry(3,time.Millisecond))
+	if err != nil || requests != 3 || len(tdb.Airports) != 9 {
+		t.Errorf("Expected the download to succeed with the third attempt but got %d: %v",requests,err)
+	}
+	failures = 5
+	if _,err = open(context.Background(),WithRetry(3,time.Millisecond)); err == nil || requests != 3 {
+		t.Errorf("Expected to give up after 3 attempts but got %d",requests)
+	}
+
+	// The delay would exceed the deadline of the context.
+	ctx,cancel := context.WithTimeout(context.Background(),time.Second)
+	defer cancel()
+	start := time.Now()
+	if _,err = open(ctx,WithRetry(3,time.Minute)); err == nil || requests != 1 || time.Since(start) > time.Second {
+		t.Errorf("Expected retries to respect the deadline of the context.")
+	}
+
+	// Client errors are not retried.
+	defer func(url string) { DefaultAirportDatUrl = url }(DefaultAirportDatUrl)
+	DefaultAirportDatUrl = srv.URL + "/missing.dat"
+	failures,requests = 0,0
+	if _,err = OpenContext(context.Background(),WithCacheDir(t.TempDir()),WithRetry(3,time.Millisecond),WithLogger(NopLogger)); err == nil || requests != 1 {
+		t.Errorf("Expected a single attempt for a missing file but got %d",requests)
+	}
+
+	// Explicit http sources are checked and retried as well.
+	explicit := func(opts ...Option) (*Database, error) {
+		requests = 0
+		opts = append(opts,WithAirportsURL(srv.URL + "/" + DefaultAirportsFilename),WithAirlinesFile("testdata/airlines.dat"),WithRoutesFile("testdata/routes.dat"),WithLogger(NopLogger))
+		return Open(opts...)
+	}
+	failures = 2
+	if _,err = explicit(); err == nil || requests != 1 {
+		t.Errorf("Expected the error page of an explicit source to fail the load but got %d requests: %v",requests,err)
+	}
+	if tdb,err = explicit(WithRetry(3,time.Millisecond)); err != nil || requests != 3 || len(tdb.Airports) != 9 {
+		t.Errorf("Expected the explicit source to succeed with the third attempt but got %d: %v",requests,err)
+	}
+}
+
+func TestDefaultCacheDir(t *testing.T) {
+	if filepath.Base(DefaultCacheDir) != "gopenflights" {
+		t.Errorf("Unexpected default cache directory: %s",DefaultCacheDir)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tdb := new(Database)
+	if err := tdb.LoadAirportData("testdata/missing.dat"); err == nil {
+		t.Errorf("Expected an error for a missing file.")
+	}
+
+	bad := filepath.Join(t.TempDir(),"routes.dat")
+	os.WriteFile(bad,[]byte("AB,214,\"JFK,3797,DUS,345,,0,332\n"),0644)
+	if err := tdb.LoadRouteData(bad); err == nil {
+		t.Errorf("Expected an error for a malformed csv file.")
+	}
+	if _,err := Open(WithAirportsFile("testdata/missing.dat")); err == nil {
+		t.Errorf("Expected an error for a missing file.")
+	}
+}
+
+func TestStreamRouteData(t *testing.T) {
+	tdb := loadTestDatabase()
+	var lh []RouteRecord
+	err := tdb.StreamRouteData("testdata/routes.dat",func(r RouteRecord) error {
+		if r.Airline == "LH" {
+			lh = append(lh,r)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Could not stream routes: %s",err)
+	}
+	if len(lh) != 6 || lh[0].AirlineP == nil || lh[0].SourceAirportP.IATA != "FRA" {
+		t.Errorf("Unexpected Lufthansa routes: %d",len(lh))
+	}
+
+	stop := fmt.Errorf("stop")
+	cnt := 0
+	err = tdb.StreamRouteData("testdata/routes.dat",func(r RouteRecord) error {
+		cnt++
+		return stop
+	})
+	if err != stop || cnt != 1 {
+		t.Errorf("Expected streaming to stop at the first error.")
+	}
+}
+
+func TestAirportCoordinatePrecision(t *testing.T) {
+	var a AirportRecord
+	if err := a.Convert([]string{"3797","John F Kennedy Intl","New York","United States","JFK","KJFK","40.639751","-73.778925","13","-5","A"}); err != nil {
+		t.Fatalf("Cannot convert airport: %s",err)
+	}
+	if a.Lat != 40.639751 || a.Long != -73.778925 {
+		t.Errorf("Coordinates are truncated: %v,%v",a.Lat,a.Long)
+	}
+}
+
+func TestConvertCollectsAllErrors(t *testing.T) {
+	var a AirportRecord
+	err := a.Convert([]string{"3797","JFK","New York","United States","JFK","KJFK","north","west","13","-5","A"})
+	if err == nil {
+		t.Fatalf("Expected an error for invalid coordinates.")
+	}
+	if msg := err.Error(); !strings.Contains(msg,"north") || !strings.Contains(msg,"west") {
+		t.Errorf("Expected both invalid fields to be reported: %s",msg)
+	}
+}
+
+func TestConcurrentReadsDuringRefresh(t *testing.T) {
+	tdb,err := Open(WithAirportsFile("testdata/airports.dat"),WithAirlinesFile("testdata/airlines.dat"),WithRoutesFile("testdata/routes.dat"))
+	if err != nil {
+		t.Fatalf("Could not open database: %s",err)
+	}
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 20; i++ {
+			tdb.Refresh()
+		}
+		close(done)
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+			if a := tdb.AirportByIATA("JFK"); a == nil {
+				t.Fatalf("JFK not found during refresh.")
+			}
+			tdb.RoutesByAirport(3797)
+			tdb.RoutesBetweenCountries("Germany","United States")
+			tdb.AirlinesBetweenCountries("United Kingdom","United States")
+			tdb.RoutesGeo()
+			tdb.RoutesGeoJSON()
+		}
+	}
+}
+
+func TestOperatingRoutes(t *testing.T) {
+	tdb := loadTestDatabase()
+	if l := len(tdb.OperatingRoutes()); l != len(tdb.Routes) - 1 {
+		t.Errorf("Expected all routes except one codeshare but got %d",l)
+	}
+	all,op := tdb.RoutesByAirport(507),tdb.OperatingRoutesByAirport(507)
+	if len(op) != len(all) - 1 {
+		t.Errorf("Expected one codeshare at LHR: %d/%d",len(op),len(all))
+	}
+	for _,r := range op {
+		if r.Codeshare {
+			t.Errorf("Codeshare route %s -> %s returned.",r.SourceAirport,r.DestAirport)
+		}
+	}
+}
+
+func TestAirportsInCountryAndCity(t *testing.T) {
+	tdb := loadTestDatabase()
+	if l := len(tdb.AirportsInCountry("Germany")); l != 3 {
+		t.Errorf("Expected 3 airports in Germany but got %d",l)
+	}
+	if l := len(tdb.AirportsInCity("Frankfurt")); l != 2 {
+		t.Errorf("Expected 2 airports in Frankfurt but got %d",l)
+	}
+	if l := len(tdb.AirportsInCountry("Atlantis")); l != 0 {
+		t.Errorf("Expected no airports in Atlantis but got %d",l)
+	}
+}
+
+func TestSearchAirports(t *testing.T) {
+	tdb := loadTestDatabase()
+	ret := tdb.SearchAirports("frankfurt")
+	if len(ret) != 2 || ret[0].IATA != "FRA" {
+		t.Errorf("Expected FRA and Frankfurt Hbf.")
+	}
+	if ret = tdb.SearchAirports("lax"); len(ret) != 1 || ret[0].IATA != "LAX" {
+		t.Errorf("Expected LAX for an IATA query.")
+	}
+	if ret = tdb.SearchAirports("LHR"); len(ret) != 1 {
+		t.Errorf("Expected LHR for an upper case query.")
+	}
+	if ret = tdb.SearchAirports("john"); len(ret) != 1 || ret[0].IATA != "JFK" {
+		t.Errorf("Expected JFK for a name prefix query.")
+	}
+	if ret = tdb.SearchAirports(""); ret == nil || len(ret) != 0 {
+		t.Errorf("Expected an empty slice for an empty query.")
+	}
+}
+
+func TestGzipSource(t *testing.T) {
+	data,err := os.ReadFile("testdata/routes.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf strings.Builder
+	gz := gzip.NewWriter(&buf)
+	gz.Write(data)
+	gz.Close()
+	path := filepath.Join(t.TempDir(),"routes.dat.gz")
+	if err = os.WriteFile(path,[]byte(buf.String()),0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tdb := loadTestDatabase()
+	if err = tdb.LoadRouteData(path); err != nil || len(tdb.Routes) != 20 {
+		t.Errorf("Expected 20 routes from the gzip file but got %d: %v",len(tdb.Routes),err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding","gzip")
+		w.Write([]byte(buf.String()))
+	}))
+	defer srv.Close()
+	if err = tdb.LoadRouteData(srv.URL + "/routes.dat"); err != nil || len(tdb.Routes) != 20 {
+		t.Errorf("Expected 20 routes from the gzip encoded response but got %d: %v",len(tdb.Routes),err)
+	}
+}
+
+func TestLoadFromReader(t *testing.T) {
+	tdb := new(Database)
+	for _,f := range []struct {
+		file string
+		load func(io.Reader) error
+	}{
+		{"testdata/airports.dat",tdb.LoadAirportsFrom},
+		{"testdata/airlines.dat",tdb.LoadAirlinesFrom},
+		{"testdata/routes.dat",tdb.LoadRoutesFrom},
+	} {
+		data,err := os.ReadFile(f.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = f.load(strings.NewReader(string(data))); err != nil {
+			t.Errorf("Cannot load %s: %s",f.file,err)
+		}
+	}
+	if len(tdb.Airports) != 9 || len(tdb.Airlines) != 8 || len(tdb.Routes) != 20 {
+		t.Errorf("Unexpected record counts: %d/%d/%d",len(tdb.Airports),len(tdb.Airlines),len(tdb.Routes))
+	}
+	if lhr := tdb.AirportByIATA("LHR"); lhr == nil || len(lhr.SourceRoutes) == 0 {
+		t.Errorf("Expected routes to be linked to LHR.")
+	}
+}
+
+func TestDownloadFileProgress(t *testing.T) {
+	body := strings.Repeat("AA,24,JFK,3797,LHR,507,,0,777\n",4000)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	var calls int
+	var last int64
+	target := filepath.Join(t.TempDir(),"routes.dat")
+	err := DownloadFileProgress(srv.URL + "/routes.dat",target,func(n int64) {
+		if n < last {
+			t.Errorf("Progress must not decrease: %d < %d",n,last)
+		}
+		calls++
+		last = n
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls < 2 || last != int64(len(body)) {
+		t.Errorf("Expected several progress calls up to %d bytes but got %d calls up to %d",len(body),calls,last)
+	}
+	if data,_ := os.ReadFile(target); string(data) != body {
+		t.Errorf("Downloaded file does not match.")
+	}
+}
+
+func TestDownloadFileUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	target := filepath.Join(t.TempDir(),"airports.dat")
+	if err := DownloadFile(url + "/airports.dat",target); err == nil {
+		t.Errorf("Expected an error for an unreachable host.")
+	}
+	if _,err := os.Stat(target); err == nil {
+		t.Errorf("Target must not be created if the request fails.")
+	}
+}
+
+func TestDownloadFileInterrupted(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Announce more content than sent to simulate a dropped connection.
+		w.Header().Set("Content-Length","1000")
+		w.Write([]byte("AA,24,JFK,3797,LHR,507,,0,777\n"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	target := filepath.Join(dir,"routes.dat")
+	os.WriteFile(target,[]byte("cached"),0644)
+	if err := DownloadFile(srv.URL + "/routes.dat",target); err == nil {
+		t.Errorf("Expected an error for an interrupted download.")
+	}
+	if data,_ := os.ReadFile(target); string(data) != "cached" {
+		t.Errorf("Target must not be replaced by a partial download: %s",data)
+	}
+	if files,_ := os.ReadDir(dir); len(files) != 1 {
+		t.Errorf("Expected temporary files to be removed but got %d files",len(files))
+	}
+}
+
+func TestRoutesSorted(t *testing.T) {
+	tdb := loadTestDatabase()
+	for _,aid := range []int{507,3797,3484} {
+		for _,rs := range [][]*RouteRecord{tdb.RoutesByAirport(aid),tdb.RoutesToAirport(aid),tdb.RoutesFromAirport(aid)} {
+			for i := 1; i < len(rs); i++ {
+				if routeLess(rs[i],rs[i - 1]) {
+					t.Errorf("Routes of airport %d are not sorted: %v",aid,rs)
+				}
+			}
+		}
+	}
+	a,b := tdb.RoutesByAirport(3797),tdb.RoutesByAirport(3797)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Errorf("Expected the same order of routes on each call.")
+		}
+	}
+}
+
+func TestParallelConvert(t *testing.T) {
+	defer func(w int) { convertWorkers = w }(convertWorkers)
+	data,err := new(Database).loadCsv("testdata/routes.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Add lines that cannot be converted in between.
+	data = append(append(data[:3:3],[]string{"XX"},[]string{"AA","24","JFK","x","LHR","507","","0",""}),data[3:]...)
+
+	convertWorkers = 1
+	serial := loadTestDatabase()
+	serial.setRouteData("test",data)
+	for _,w := range []int{2,3,7,100} {
+		convertWorkers = w
+		tdb := loadTestDatabase()
+		tdb.setRouteData("test",data)
+		if len(tdb.Routes) != len(serial.Routes) || len(tdb.report.Warnings) != len(serial.report.Warnings) {
+			t.Fatalf("Unexpected result with %d workers: %d routes, %d warnings",w,len(tdb.Routes),len(tdb.report.Warnings))
+		}
+		for i := range tdb.Routes {
+			if !reflect.DeepEqual(tdb.Routes[i].fields(),serial.Routes[i].fields()) {
+				t.Errorf("Route %d differs with %d workers: %v",i,w,tdb.Routes[i].fields())
+			}
+		}
+		for i,msg := range tdb.report.Warnings {
+			if msg != serial.report.Warnings[i] {
+				t.Errorf("Warning %d differs with %d workers: %s",i,w,msg)
+			}
+		}
+	}
+}
+
+// benchmarkRouteData returns the test routes repeated to the size of the full dataset.
+func benchmarkRouteData(b *testing.B) [][]string {
+	data,err := new(Database).loadCsv("testdata/routes.dat")
+	if err != nil {
+		b.Fatal(err)
+	}
+	var ret [][]string
+	for len(ret) < 67000 {
+		ret = append(ret,data...)
+	}
+	return ret
+}
+
+func BenchmarkLoadRouteData(b *testing.B) {
+	defer func(w int) { convertWorkers = w }(convertWorkers)
+	data := benchmarkRouteData(b)
+	tdb := loadTestDatabase()
+	tdb.SetLogger(NopLogger)
+	for _,bm := range []struct {
+		name string
+		workers int
+	}{{"serial",1},{"parallel",0}} {
+		b.Run(bm.name,func(b *testing.B) {
+			convertWorkers = bm.workers
+			for i := 0; i < b.N; i++ {
+				tdb.report = LoadReport{}
+				tdb.setRouteData("bench",data)
+			}
+		})
+	}
+}
+
+func TestCompactRoutes(t *testing.T) {
+	tdb := loadTestDatabase()
+	before := make([][]string,len(tdb.Routes))
+	for i := range tdb.Routes {
+		before[i] = tdb.Routes[i].fields()
+	}
+	tdb.CompactRoutes()
+	for i := range tdb.Routes {
+		r := &tdb.Routes[i]
+		if r.SourceAirportP != nil && r.SourceAirport != "" || r.AirlineP != nil && r.Airline != "" {
+			t.Errorf("Codes of route %d have not been dropped.",i)
+		}
+		if !reflect.DeepEqual(r.fields(),before[i]) {
+			t.Errorf("Route %d differs after compaction: %v/%v",i,r.fields(),before[i])
+		}
+	}
+	// The airline of ZZ LHR -> DUS and the destination of LH FRA -> QQQ are unknown.
+	for _,r := range tdb.RoutesFromAirport(507) {
+		if r.AirlineId == 9999 && (r.Airline != "ZZ" || r.AirlineCode() != "ZZ" || r.DestCode() != "DUS") {
+			t.Errorf("Unexpected codes of unresolved route: %s/%s",r.AirlineCode(),r.DestCode())
+		}
+	}
+	for _,r := range tdb.RoutesFromAirport(340) {
+		if r.DestAirportId == 99999 && r.DestAirport != "QQQ" {
+			t.Errorf("Unresolved destination code has been dropped.")
+		}
+	}
+}
+
+func TestDelimiter(t *testing.T) {
+	data,err := new(Database).loadCsv("testdata/airports.dat")
+	if err != nil {
+		t.Fatalf("Could not load airports: %s",err)
+	}
+	path := filepath.Join(t.TempDir(),"airports.tsv")
+	f,err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Could not create file: %s",err)
+	}
+	w := csv.NewWriter(f)
+	w.Comma = '\t'
+	w.WriteAll(data)
+	f.Close()
+
+	expected,tsv := new(Database),new(Database)
+	expected.LoadAirportData("testdata/airports.dat")
+	tsv.SetDelimiter('\t')
+	if err = tsv.LoadAirportData(path); err != nil {
+		t.Fatalf("Could not load tab separated airports: %s",err)
+	}
+	if len(tsv.Airports) != 9 || !reflect.DeepEqual(tsv.Airports,expected.Airports) {
+		t.Errorf("Expected tab separated airports to load identically.")
+	}
+	if tsv.AirportsByIATA["FRA"] == nil {
+		t.Errorf("Expected tab separated airports to be indexed.")
+	}
+}
+
+func TestReindex(t *testing.T) {
+	tdb := loadTestDatabase()
+	tdb.AirportsByIATA["FRA"].IATA = "frx"
+	tdb.Airports = append(tdb.Airports,AirportRecord{Id: 9999, Name: "Test", IATA: "TST", Country: "Germany"})
+	tdb.Routes = append(tdb.Routes,RouteRecord{Airline: "LH", AirlineId: 3090, SourceAirport: "FRX", SourceAirportId: 340, DestAirport: "TST", DestAirportId: 9999})
+	tdb.Reindex()
+
+	if tdb.AirportByIATA("FRA") != nil || tdb.AirportByIATA("FRX") == nil {
+		t.Errorf("Expected FRA to be indexed as FRX.")
+	}
+	tst := tdb.AirportByID(9999)
+	if tst == nil || tdb.AirportsByIATA["TST"] != tst || len(tdb.AirportsByCountry["Germany"]) != 4 {
+		t.Fatalf("Expected the new airport to be indexed.")
+	}
+	if len(tst.DestRoutes) != 1 || len(tdb.RoutesBetween(340,9999)) != 1 {
+		t.Errorf("Expected the new route to be linked.")
+	}
+	if len(tdb.RoutesByAirline(3090)) != 7 {
+		t.Errorf("Expected the new route at Lufthansa.")
+	}
+	for i := range tdb.Routes {
+		if r := &tdb.Routes[i]; r.SourceAirportP != nil && r.SourceAirportP != tdb.AirportByID(r.SourceAirportId) {
+			t.Fatalf("Expected all routes to be relinked.")
+		}
+	}
+}
+
+func TestLocalTime(t *testing.T) {
+	utc := time.Date(2020,7,1,12,0,0,0,time.UTC)
+	legacy := &AirportRecord{Id: 1, Timezone: 5.5}
+	lt,err := legacy.LocalTime(utc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s",err)
+	}
+	if name,off := lt.Zone(); lt.Hour() != 17 || lt.Minute() != 30 || off != 19800 || name != "UTC+5:30" {
+		t.Errorf("Unexpected local time of legacy airport: %s",lt)
+	}
+	if lt,_ = (&AirportRecord{Timezone: -5}).LocalTime(utc); lt.Hour() != 7 || lt.Location().String() != "UTC-5" {
+		t.Errorf("Unexpected local time with negative offset: %s",lt)
+	}
+	if _,err = (&AirportRecord{Tz: "Nowhere/Unknown"}).LocalTime(utc); err == nil {
+		t.Errorf("Expected an error for an unknown time zone.")
+	}
+
+	jfk := loadTestDatabase().AirportByIATA("JFK")
+	if _,err = jfk.Location(); err != nil {
+		t.Skipf("Time zone database not available: %s",err)
+	}
+	// JFK observes DST, so the summer offset differs from the Timezone field.
+	if lt,err = jfk.LocalTime(utc); err != nil || lt.Hour() != 8 {
+		t.Errorf("Unexpected local time of JFK: %s (%v)",lt,err)
+	}
+	if lt,_ = jfk.LocalTime(time.Date(2020,1,1,12,0,0,0,time.UTC)); lt.Hour() != 7 {
+		t.Errorf("Unexpected winter local time of JFK: %s",lt)
+	}
+}
+
+func TestRoutesByEquipment(t *testing.T) {
+	tdb := loadTestDatabase()
+	routes := tdb.RoutesByEquipment("744")
+	if len(routes) != 8 {
+		t.Errorf("Expected 8 routes operated with a 747-400 but got %d",len(routes))
+	}
+	for i,r := range routes {
+		if !strings.Contains(" " + r.Equipment + " "," 744 ") {
+			t.Errorf("Unexpected equipment of route %s -> %s: %s",r.SourceCode(),r.DestCode(),r.Equipment)
+		}
+		if i > 0 && routes[i - 1] == r {
+			t.Errorf("Route %s -> %s is returned twice.",r.SourceCode(),r.DestCode())
+		}
+	}
+	if routes = tdb.RoutesByEquipment("388"); len(routes) != 2 || routes[0].Airline != "QF" {
+		t.Errorf("Expected the A380 routes of Qantas.")
+	}
+	if routes = tdb.RoutesByEquipment("74"); len(routes) != 0 {
+		t.Errorf("Expected no routes for a partial code but got %d",len(routes))
+	}
+}
+
+func TestRoute(t *testing.T) {
+	tdb := loadTestDatabase()
+	r := tdb.Route(1355,507,3797)
+	if r == nil || r.Airline != "BA" || r.SourceAirport != "LHR" || r.DestAirport != "JFK" {
+		t.Fatalf("Unexpected route: %v",r)
+	}
+	if r != tdb.FindRoute(func(c *RouteRecord) bool { return c.AirlineId == 1355 && c.SourceAirportId == 507 && c.DestAirportId == 3797 }) {
+		t.Errorf("Expected the route to point into Routes.")
+	}
+	if tdb.Route(1355,3797,340) != nil || tdb.Route(3090,340,123456) != nil {
+		t.Errorf("Expected no route for unknown keys.")
+	}
+	// Routes with unknown airlines are indexed as well.
+	if r = tdb.Route(9999,507,345); r == nil || r.Airline != "ZZ" {
+		t.Errorf("Expected the route of the unknown airline.")
+	}
+
+	// The first of duplicate routes is returned.
+	dup := tdb.Routes[0]
+	dup.Equipment = "320"
+	if err := tdb.AddRoute(dup); err != nil {
+		t.Fatal(err)
+	}
+	if r = tdb.Route(dup.AirlineId,dup.SourceAirportId,dup.DestAirportId); r != &tdb.Routes[0] {
+		t.Errorf("Expected the first of duplicate routes.")
+	}
+	if !tdb.RemoveRoute(r) {
+		t.Fatalf("Could not remove route.")
+	}
+	if r = tdb.Route(dup.AirlineId,dup.SourceAirportId,dup.DestAirportId); r == nil || r.Equipment != "320" {
+		t.Errorf("Expected the added route after removing the first one but got %v",r)
+	}
+
+	// The first added route is returned even if a later one has been moved before it.
+	tdb = loadTestDatabase()
+	dup = tdb.Routes[1]
+	for _,e := range []string{"320","738"} {
+		dup.Equipment = e
+		if err := tdb.AddRoute(dup); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !tdb.RemoveRoute(&tdb.Routes[0]) || tdb.Routes[0].Equipment != "738" {
+		t.Fatalf("Expected the last added route to be moved to the front.")
+	}
+	if r = tdb.Route(dup.AirlineId,dup.SourceAirportId,dup.DestAirportId); r != &tdb.Routes[1] {
+		t.Errorf("Expected the loaded one of the duplicate routes.")
+	}
+	if !tdb.RemoveRoute(r) {
+		t.Fatalf("Could not remove route.")
+	}
+	if r = tdb.Route(dup.AirlineId,dup.SourceAirportId,dup.DestAirportId); r == nil || r.Equipment != "320" {
+		t.Errorf("Expected the first added of the remaining duplicate routes but got %v",r)
+	}
+}
+
+func TestUnknownAirlinePolicy(t *testing.T) {
+	open := func(policy UnknownAirlinePolicy) *Database {
+		tdb,err := Open(WithAirportsFile("testdata/airports.dat"),WithRoutesFile("testdata/routes.dat"),WithAirlinesFile("testdata/airlines.dat"),WithLogger(NopLogger),WithUnknownAirlinePolicy(policy))
+		if err != nil {
+			t.Fatalf("Could not open database: %s",err)
+		}
+		return tdb
+	}
+	// ZZ 9999 LHR -> DUS is the only route of an unknown airline.
+	keep := open(UnknownAirlineKeep)
+	if r := keep.Route(9999,507,345); r == nil || r.AirlineP != nil {
+		t.Errorf("Expected the route of the unknown airline to be kept.")
+	}
+
+	skip := open(UnknownAirlineSkip)
+	if len(skip.Routes) != len(keep.Routes) - 1 || skip.Route(9999,507,345) != nil {
+		t.Errorf("Expected the route of the unknown airline to be skipped.")
+	}
+	if skip.report.Routes.Skipped != keep.report.Routes.Skipped + 1 {
+		t.Errorf("Expected the skipped route in the load report.")
+	}
+	if err := skip.AddRoute(RouteRecord{AirlineId: 9999, SourceAirportId: 507, DestAirportId: 345}); err == nil {
+		t.Errorf("Expected an error for a route of an unknown airline.")
+	}
+	var streamed int
+	skip.StreamRouteData("testdata/routes.dat",func(r RouteRecord) error {
+		if r.AirlineP == nil {
+			t.Errorf("Unexpected streamed route of unknown airline %d.",r.AirlineId)
+		}
+		streamed++
+		return nil
+	})
+	if streamed != len(skip.Routes) {
+		t.Errorf("Expected %d streamed routes but got %d",len(skip.Routes),streamed)
+	}
+
+	ph := open(UnknownAirlinePlaceholder)
+	r := ph.Route(9999,507,345)
+	if r == nil || r.AirlineP == nil {
+		t.Fatalf("Expected a placeholder airline.")
+	}
+	if al := r.AirlineP; al.Id != 9999 || al.Name != "Unknown" || al.IATA != "ZZ" || !al.Routes[r] {
+		t.Errorf("Unexpected placeholder airline: %v",al)
+	}
+	if ph.AirlineByID(9999) != nil || len(ph.Airlines) != len(keep.Airlines) {
+		t.Errorf("Placeholder airlines must not be part of the airlines.")
+	}
+	for i := range ph.Routes {
+		if ph.Routes[i].AirlineP == nil {
+			t.Errorf("Expected all routes to reference an airline.")
+		}
+	}
+	if len(ph.Validate()) != len(keep.Validate()) {
+		t.Errorf("Expected routes with placeholder airlines to be reported by Validate.")
+	}
+}
@@ -1,8 +1,30 @@
 package gopenflights
 
+import(
+        "fmt"
+        "math"
+        "sort"
+)
+
+// EarthRadius is the mean earth radius in km used for all distance calculations.
+const EarthRadius = 6371.0
+
+// Haversine returns the great-circle distance in km between the two given coordinates
+// in degrees using the haversine formula.
+func Haversine(lat1, long1, lat2, long2 float64) float64 {
+        rad := math.Pi / 180
+        dlat := (lat2 - lat1) * rad
+        dlong := (long2 - long1) * rad
+        h := math.Sin(dlat/2) * math.Sin(dlat/2) +
+                math.Cos(lat1*rad) * math.Cos(lat2*rad) * math.Sin(dlong/2) * math.Sin(dlong/2)
+        return 2 * EarthRadius * math.Asin(math.Sqrt(h))
+}
+
 // AirportsGeo returns a list of all airport geo coordinates.
 // In addition to that it contains the amount of routes from/to this
 // airport are registered.
+// Note that the latitude is negated as expected by the WebGL globe this output was made for.
+// Use AirportsCoordinates or AirportsGeoJSON for sign-correct coordinates.
 func (o *Database) AirportsGeo() (ret [][]float64) {
         ret = make([][]float64,len(o.Airports))
         for i,a := range o.Airports {
@@ -14,34 +36,303 @@ func (o *Database) AirportsGeo() (ret [][]float64) {
         return
 }
 
+// AirportsCoordinates returns the [longitude, latitude] pairs of all airports in the
+// order of the Airports slice. In contrast to AirportsGeo the latitude is not negated.
+func (o *Database) AirportsCoordinates() (ret [][2]float64) {
+        ret = make([][2]float64,len(o.Airports))
+        for i := range o.Airports {
+                ret[i] = [2]float64{o.Airports[i].Long,o.Airports[i].Lat}
+        }
+        return
+}
+
 // RoutesGeo returns the Geo coordinates of all routes without duplicates.
 // Back and forth rooutes are counted once.
-func (o *Database) RoutesGeo() [][]float64 {
+// Like in AirportsGeo the latitudes are negated. Use RoutesGeoJSON for sign-correct coordinates.
+func (o *Database) RoutesGeo() (ret [][]float64) {
+        o.mu.RLock()
+        defer o.mu.RUnlock()
         type coords struct {
                 long,lat float64
         }
-        ret := make([][]float64,len(o.Routes))
         done := make(map[coords]coords)
-        idx := 0
-        for i,r := range o.Routes {
-                ret[i] = make([]float64,4)
-                s:= r.SourceAirportP
-                d:= r.DestAirportP
-                if s != nil && s != nil {
+        for i := range o.Routes {
+                s:= o.Routes[i].SourceAirportP
+                d:= o.Routes[i].DestAirportP
+                if s != nil && d != nil {
                         f := coords{s.Long,-s.Lat}
                         t := coords{d.Long,-d.Lat}
                         if done[f] != t {
-                                ret[idx][0] = s.Long
-                                ret[idx][1] = -s.Lat
-                                ret[idx][2] = d.Long
-                                ret[idx][3] = -d.Lat
+                                ret = append(ret,[]float64{s.Long,-s.Lat,d.Long,-d.Lat})
                                 done[f] = t
                                 done[t] = f
-                                idx++
                         }
                 }
         }
-        return ret[:idx]
+        return
+}
+
+
+
+// Distance returns the great-circle distance of the route in km.
+// An error is returned if the source or destination airport of the route is not resolved.
+func (r *RouteRecord) Distance() (float64, error) {
+        s := r.SourceAirportP
+        d := r.DestAirportP
+        if s == nil || d == nil {
+                return 0,fmt.Errorf("Airports of route %s -> %s are not resolved.",r.SourceCode(),r.DestCode())
+        }
+        return s.DistanceTo(d),nil
+}
+
+// DistanceTo returns the great-circle distance in km to the given airport.
+func (a *AirportRecord) DistanceTo(b *AirportRecord) float64 {
+        return Haversine(a.Lat,a.Long,b.Lat,b.Long)
+}
+
+// AirlineAverageRouteDistance returns the average great-circle distance in km of all
+// routes of the given airline id. Routes with unresolved airports are skipped.
+func (o *Database) AirlineAverageRouteDistance(airlineId int) float64 {
+        sum := 0.0
+        cnt := 0
+        for _,r := range o.RoutesByAirline(airlineId) {
+                if d,err := r.Distance(); err == nil {
+                        sum += d
+                        cnt++
+                }
+        }
+        if cnt == 0 {
+                return 0
+        }
+        return sum / float64(cnt)
+}
+
+// vec3 is a point on the unit sphere.
+type vec3 [3]float64
+
+// unitVector returns the unit vector of the given coordinate.
+func unitVector(lat, long float64) vec3 {
+        rad := math.Pi / 180
+        return vec3{
+                math.Cos(lat*rad) * math.Cos(long*rad),
+                math.Cos(lat*rad) * math.Sin(long*rad),
+                math.Sin(lat*rad)}
+}
+
+func (a vec3) cross(b vec3) vec3 {
+        return vec3{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+}
+
+func (a vec3) dot(b vec3) float64 {
+        return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+func (a vec3) norm() float64 {
+        return math.Sqrt(a.dot(a))
+}
+
+// angle returns the angle in radians between the two vectors.
+func (a vec3) angle(b vec3) float64 {
+        return math.Atan2(a.cross(b).norm(),a.dot(b))
+}
+
+// onArc reports whether p lies on the minor great-circle arc from a to b.
+func onArc(p, a, b vec3) bool {
+        return math.Abs(a.angle(p) + p.angle(b) - a.angle(b)) < 1e-9
+}
+
+// RoutesCross reports whether the great-circle paths of the two routes intersect.
+// Routes sharing an airport are considered crossing. If any airport of the routes
+// is not resolved false is returned.
+func RoutesCross(a, b *RouteRecord) bool {
+        if a.SourceAirportP == nil || a.DestAirportP == nil || b.SourceAirportP == nil || b.DestAirportP == nil {
+                return false
+        }
+        a1 := unitVector(a.SourceAirportP.Lat,a.SourceAirportP.Long)
+        a2 := unitVector(a.DestAirportP.Lat,a.DestAirportP.Long)
+        b1 := unitVector(b.SourceAirportP.Lat,b.SourceAirportP.Long)
+        b2 := unitVector(b.DestAirportP.Lat,b.DestAirportP.Long)
+
+        l := a1.cross(a2).cross(b1.cross(b2))
+        n := l.norm()
+        if n < 1e-12 {
+                // Both paths are on the same great circle; they cross if they overlap.
+                return onArc(b1,a1,a2) || onArc(b2,a1,a2) || onArc(a1,b1,b2) || onArc(a2,b1,b2)
+        }
+        l = vec3{l[0]/n, l[1]/n, l[2]/n}
+        for _,p := range []vec3{l, {-l[0], -l[1], -l[2]}} {
+                if onArc(p,a1,a2) && onArc(p,b1,b2) {
+                        return true
+                }
+        }
+        return false
 }
 
+// hasPosition reports whether the airport has a known position. Airports located
+// exactly at (0,0) are considered to have no valid coordinates.
+func (a *AirportRecord) hasPosition() bool {
+        return a.Lat != 0 || a.Long != 0
+}
+
+// AirportsSortedByDistance returns all airports sorted by ascending great-circle distance
+// from the given airport id. The airport itself and airports without valid coordinates
+// are not included. An error is returned if the airport is unknown or has no valid coordinates.
+func (o *Database) AirportsSortedByDistance(fromId int) ([]*AirportRecord, error) {
+        from := o.Airport(fromId)
+        if from == nil {
+                return nil,fmt.Errorf("Unknown airportId: %d",fromId)
+        }
+        if !from.hasPosition() {
+                return nil,fmt.Errorf("Coordinates of airportId %d are not specified.",fromId)
+        }
+        ret := make([]*AirportRecord,0,len(o.Airports))
+        dist := make(map[*AirportRecord]float64,len(o.Airports))
+        for i := range o.Airports {
+                if a := &o.Airports[i]; a != from && a.hasPosition() {
+                        ret = append(ret,a)
+                        dist[a] = from.DistanceTo(a)
+                }
+        }
+        sort.SliceStable(ret,func(i,j int) bool { return dist[ret[i]] < dist[ret[j]] })
+        return ret,nil
+}
+
+// DistanceMatrix returns the great-circle distances in km between all pairs of the given
+// airport ids. Element [i][j] is the distance from airportIds[i] to airportIds[j], so the
+// matrix is symmetric with zeros on the diagonal. An error is returned if an airport is unknown.
+func (o *Database) DistanceMatrix(airportIds []int) ([][]float64, error) {
+        airports := make([]*AirportRecord,len(airportIds))
+        for i,id := range airportIds {
+                if airports[i] = o.Airport(id); airports[i] == nil {
+                        return nil,fmt.Errorf("Unknown airportId: %d",id)
+                }
+        }
+        ret := make([][]float64,len(airports))
+        for i := range ret {
+                ret[i] = make([]float64,len(airports))
+                for j := 0; j < i; j++ {
+                        ret[i][j] = airports[i].DistanceTo(airports[j])
+                        ret[j][i] = ret[i][j]
+                }
+        }
+        return ret,nil
+}
+
+// NearestAirport returns the airport closest to the given coordinate and its distance in km.
+// Airports without valid coordinates are skipped. If there is no airport, nil is returned.
+func (o *Database) NearestAirport(lat, long float64) (ret *AirportRecord, dist float64) {
+        for i := range o.Airports {
+                a := &o.Airports[i]
+                if !a.hasPosition() {
+                        continue
+                }
+                if d := Haversine(lat,long,a.Lat,a.Long); ret == nil || d < dist {
+                        ret,dist = a,d
+                }
+        }
+        return
+}
+
+// BearingTo returns the initial great-circle bearing in degrees (0-360) from the
+// airport to the given one. 0 is north, 90 is east.
+func (a *AirportRecord) BearingTo(b *AirportRecord) float64 {
+        rad := math.Pi / 180
+        lat1,lat2 := a.Lat*rad,b.Lat*rad
+        dlong := (b.Long - a.Long) * rad
+        y := math.Sin(dlong) * math.Cos(lat2)
+        x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dlong)
+        return math.Mod(math.Atan2(y,x)/rad + 360,360)
+}
 
+// BearingBetween returns the initial great-circle bearing in degrees (0-360) from
+// the source to the destination airport id.
+// An error is returned if one of the airports is unknown.
+func (o *Database) BearingBetween(srcId, dstId int) (float64, error) {
+        s := o.Airport(srcId)
+        d := o.Airport(dstId)
+        if s == nil || d == nil {
+                return 0,fmt.Errorf("Unknown airportId: %d or %d",srcId,dstId)
+        }
+        return s.BearingTo(d),nil
+}
+
+// PointAlong returns the coordinate at the given fraction (0-1) of the great-circle
+// path from the airport to the given one.
+func (a *AirportRecord) PointAlong(b *AirportRecord, fraction float64) (lat, long float64) {
+        return intermediate(a.Lat,a.Long,b.Lat,b.Long,fraction)
+}
+
+// MidpointTo returns the midpoint of the great-circle path from the airport to the given one.
+func (a *AirportRecord) MidpointTo(b *AirportRecord) (lat, long float64) {
+        return a.PointAlong(b,0.5)
+}
+
+// ArcPoints returns segments+1 [longitude, latitude] points sampled evenly along the
+// great-circle path from the source to the destination airport of the route. Longitudes
+// are within [-180, 180], so paths crossing the antimeridian wrap around. Use ArcLines
+// to get them split at the antimeridian. If segments is less than 1, one segment is used.
+// If an airport of the route is not resolved, nil is returned.
+func (r *RouteRecord) ArcPoints(segments int) [][2]float64 {
+        s,d := r.SourceAirportP,r.DestAirportP
+        if s == nil || d == nil {
+                return nil
+        }
+        if segments < 1 {
+                segments = 1
+        }
+        ret := make([][2]float64,segments + 1)
+        for i := range ret {
+                lat,long := s.PointAlong(d,float64(i) / float64(segments))
+                ret[i] = [2]float64{long,lat}
+        }
+        // Avoid rounding errors at the endpoints.
+        ret[0] = [2]float64{s.Long,s.Lat}
+        ret[segments] = [2]float64{d.Long,d.Lat}
+        return ret
+}
+
+// ArcLines returns the points of ArcPoints split into separate polylines where the
+// path crosses the antimeridian. The split points at longitude 180 and -180 are added
+// to both adjacent polylines. If an airport of the route is not resolved, nil is returned.
+func (r *RouteRecord) ArcLines(segments int) (ret [][][2]float64) {
+        points := r.ArcPoints(segments)
+        if points == nil {
+                return
+        }
+        line := [][2]float64{points[0]}
+        for i := 1; i < len(points); i++ {
+                p,q := points[i - 1],points[i]
+                if math.Abs(q[0] - p[0]) > 180 {
+                        // Unwrap the longitude of q and interpolate the latitude at the antimeridian.
+                        edge,shift := 180.0,360.0
+                        if p[0] < 0 {
+                                edge,shift = -180,-360
+                        }
+                        t := (edge - p[0]) / (q[0] + shift - p[0])
+                        lat := p[1] + t * (q[1] - p[1])
+                        ret = append(ret,append(line,[2]float64{edge,lat}))
+                        line = [][2]float64{{-edge,lat}}
+                }
+                line = append(line,q)
+        }
+        return append(ret,line)
+}
+
+// intermediate returns the coordinate at the given fraction of the great-circle path
+// between the two given coordinates.
+func intermediate(lat1, long1, lat2, long2, fraction float64) (lat, long float64) {
+        p1 := unitVector(lat1,long1)
+        p2 := unitVector(lat2,long2)
+        delta := p1.angle(p2)
+        if math.Sin(delta) < 1e-12 {
+                // Identical or antipodal points have no unique path.
+                return lat1,long1
+        }
+        f1 := math.Sin((1 - fraction) * delta) / math.Sin(delta)
+        f2 := math.Sin(fraction * delta) / math.Sin(delta)
+        x := f1*p1[0] + f2*p2[0]
+        y := f1*p1[1] + f2*p2[1]
+        z := f1*p1[2] + f2*p2[2]
+        deg := 180 / math.Pi
+        return math.Atan2(z,math.Sqrt(x*x + y*y)) * deg,math.Atan2(y,x) * deg
+}
@@ -0,0 +1,310 @@
+package gopenflights
+
+import(
+	"math"
+	"testing"
+)
+
+func TestHaversine(t *testing.T) {
+	if d := Haversine(0,0,0,90); math.Abs(d - math.Pi / 2 * EarthRadius) > 1e-6 {
+		t.Errorf("Expected a quarter of the equator but got %f",d)
+	}
+	if d := Haversine(90,0,-90,0); math.Abs(d - math.Pi * EarthRadius) > 1e-6 {
+		t.Errorf("Expected half a meridian but got %f",d)
+	}
+	if d := Haversine(51.4706,-0.461941,51.4706,-0.461941); d != 0 {
+		t.Errorf("Expected no distance between identical points but got %f",d)
+	}
+	// LHR <-> JFK is about 5540km.
+	if d := Haversine(51.4706,-0.461941,40.63980103,-73.77890015); math.Abs(d - 5540) > 10 {
+		t.Errorf("Unexpected distance of LHR <-> JFK: %f",d)
+	}
+}
+
+func TestAirlineAverageRouteDistance(t *testing.T) {
+	tdb := loadTestDatabase()
+	// Air Berlin only flies JFK <-> DUS which is about 6000km.
+	if d := tdb.AirlineAverageRouteDistance(214); math.Abs(d - 6000) > 100 {
+		t.Errorf("Unexpected average route distance of Air Berlin: %f",d)
+	}
+	if d := tdb.AirlineAverageRouteDistance(2); d != 0 {
+		t.Errorf("Airline without routes must have an average distance of 0 but got %f",d)
+	}
+}
+
+func TestAirportsCoordinates(t *testing.T) {
+	tdb := loadTestDatabase()
+	coords := tdb.AirportsCoordinates()
+	if len(coords) != len(tdb.Airports) {
+		t.Fatalf("Expected %d coordinates but got %d",len(tdb.Airports),len(coords))
+	}
+	// SYD is located in the southern and eastern hemisphere.
+	for i,a := range tdb.Airports {
+		if a.IATA == "SYD" && (coords[i][0] <= 0 || coords[i][1] >= 0) {
+			t.Errorf("Unexpected coordinates of SYD: %v",coords[i])
+		}
+	}
+}
+
+func TestAirportsSortedByDistance(t *testing.T) {
+	tdb := loadTestDatabase()
+	ret,err := tdb.AirportsSortedByDistance(340)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Frankfurt Hauptbahnhof is closest to FRA, followed by DUS and LHR.
+	if len(ret) != len(tdb.Airports) - 1 || ret[0].Id != 8950 || ret[1].IATA != "DUS" || ret[2].IATA != "LHR" {
+		t.Errorf("Unexpected order of airports near FRA: %v",ret)
+	}
+	for i := 1; i < len(ret); i++ {
+		if ret[i].Id == 340 {
+			t.Errorf("Reference airport must not be included.")
+		}
+		if Haversine(50.033333,8.570556,ret[i - 1].Lat,ret[i - 1].Long) > Haversine(50.033333,8.570556,ret[i].Lat,ret[i].Long) {
+			t.Errorf("Airports are not sorted by distance.")
+		}
+	}
+	if _,err = tdb.AirportsSortedByDistance(42); err == nil {
+		t.Errorf("Expected an error for an unknown airport.")
+	}
+}
+
+func TestRoutesCross(t *testing.T) {
+	tdb := loadTestDatabase()
+	route := func(src, dst string) *RouteRecord {
+		for _,r := range tdb.AllRoutes() {
+			if r.SourceAirport == src && r.DestAirport == dst {
+				return r
+			}
+		}
+		t.Fatalf("Route %s -> %s not found.",src,dst)
+		return nil
+	}
+
+	if !RoutesCross(route("JFK","FRA"),route("LHR","DUS")) {
+		t.Errorf("JFK -> FRA and LHR -> DUS are expected to cross.")
+	}
+	if RoutesCross(route("JFK","LAX"),route("FRA","HND")) {
+		t.Errorf("JFK -> LAX and FRA -> HND are not expected to cross.")
+	}
+	if RoutesCross(route("JFK","DUS"),route("FRA","QQQ")) {
+		t.Errorf("Unresolved routes must never cross.")
+	}
+}
+
+func TestRouteDistance(t *testing.T) {
+	tdb := loadTestDatabase()
+	var longest *RouteRecord
+	max := 0.0
+	for _,r := range tdb.AllRoutes() {
+		d,err := r.Distance()
+		if r.DestAirportP == nil {
+			if err == nil {
+				t.Errorf("Expected an error for unresolved route %s -> %s",r.SourceAirport,r.DestAirport)
+			}
+			continue
+		}
+		if d > max {
+			longest,max = r,d
+		}
+	}
+	if longest == nil || longest.SourceAirport != "SYD" && longest.DestAirport != "SYD" {
+		t.Errorf("Expected the longest route to be SYD <-> LAX.")
+	}
+	if math.Abs(max - 12050) > 100 {
+		t.Errorf("Unexpected distance of SYD <-> LAX: %f",max)
+	}
+}
+
+func TestNearestAirport(t *testing.T) {
+	tdb := loadTestDatabase()
+	// Cologne is closer to Duesseldorf than to Frankfurt.
+	if a,d := tdb.NearestAirport(50.9375,6.9603); a == nil || a.IATA != "DUS" || d > 50 {
+		t.Errorf("Expected DUS to be the nearest airport of Cologne.")
+	}
+	if a,_ := new(Database).NearestAirport(0,0); a != nil {
+		t.Errorf("Expected no airport in an empty database.")
+	}
+}
+
+func TestNearestAirports(t *testing.T) {
+	tdb := loadTestDatabase()
+	// Nearest airports of Cologne.
+	lat,long := 50.9375,6.9603
+	near := tdb.NearestAirports(lat,long,3)
+	if len(near) != 3 {
+		t.Fatalf("Expected 3 airports but got %d",len(near))
+	}
+	if near[0].IATA != "DUS" || near[1].Name != "Frankfurt Hauptbahnhof" || near[2].IATA != "FRA" {
+		t.Errorf("Unexpected order of nearest airports: %s, %s, %s",near[0].Name,near[1].Name,near[2].Name)
+	}
+	if a,_ := tdb.NearestAirport(lat,long); a != near[0] {
+		t.Errorf("NearestAirports does not match NearestAirport.")
+	}
+	if all := tdb.NearestAirports(lat,long,100); len(all) != len(tdb.Airports) {
+		t.Errorf("Expected all %d airports but got %d",len(tdb.Airports),len(all))
+	}
+}
+
+func TestNearestAirportsMatchesLinearScan(t *testing.T) {
+	tdb := loadTestDatabase()
+	for lat := -80.0; lat <= 80; lat += 20 {
+		for long := -170.0; long <= 170; long += 20 {
+			near := tdb.NearestAirports(lat,long,4)
+			for i := 1; i < len(near); i++ {
+				if Haversine(lat,long,near[i - 1].Lat,near[i - 1].Long) > Haversine(lat,long,near[i].Lat,near[i].Long) {
+					t.Errorf("Airports near %f,%f are not sorted by distance.",lat,long)
+				}
+			}
+			if a,_ := tdb.NearestAirport(lat,long); a != near[0] {
+				t.Errorf("Nearest airport of %f,%f does not match linear scan: %s != %s",lat,long,near[0].Name,a.Name)
+			}
+		}
+	}
+}
+
+func TestAirportsWithinRadius(t *testing.T) {
+	tdb := loadTestDatabase()
+	// Airports within 200km of Cologne.
+	ret := tdb.AirportsWithinRadius(50.9375,6.9603,200)
+	if len(ret) != 3 || ret[0].IATA != "DUS" {
+		t.Errorf("Expected DUS, FRA and Frankfurt Hbf but got %d airports.",len(ret))
+	}
+	if ret := tdb.AirportsWithinRadius(50.9375,6.9603,30000); len(ret) != len(tdb.Airports) {
+		t.Errorf("Expected all airports but got %d",len(ret))
+	}
+	for _,a := range tdb.AirportsWithinRadius(40,-74,6000) {
+		if d := Haversine(40,-74,a.Lat,a.Long); d > 6000 {
+			t.Errorf("Airport %s is outside of the radius: %f",a.Name,d)
+		}
+	}
+}
+
+func TestRoutesGeoUnknownDestination(t *testing.T) {
+	// The test data contains a route to the unknown airportId 99999.
+	tdb := loadTestDatabase()
+	for _,r := range tdb.RoutesGeo() {
+		if len(r) != 4 {
+			t.Errorf("Unexpected route coordinates: %v",r)
+		}
+	}
+}
+
+func BenchmarkRoutesGeo(b *testing.B) {
+	tdb := loadTestDatabase()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tdb.RoutesGeo()
+	}
+}
+
+func TestBearingTo(t *testing.T) {
+	origin := &AirportRecord{}
+	for _,c := range []struct {
+		lat,long,bearing float64
+	}{{0,10,90},{10,0,0},{0,-10,270},{-10,0,180}} {
+		if b := origin.BearingTo(&AirportRecord{Lat: c.lat, Long: c.long}); math.Abs(b - c.bearing) > 1e-9 {
+			t.Errorf("Expected bearing %f to %f,%f but got %f",c.bearing,c.lat,c.long,b)
+		}
+	}
+
+	tdb := loadTestDatabase()
+	// Frankfurt is south east of Duesseldorf.
+	if b,err := tdb.BearingBetween(345,340); err != nil || b < 90 || b > 180 {
+		t.Errorf("Unexpected bearing from DUS to FRA: %f",b)
+	}
+	if _,err := tdb.BearingBetween(345,123456); err == nil {
+		t.Errorf("Expected an error for an unknown airport.")
+	}
+}
+
+func TestMidpointTo(t *testing.T) {
+	tdb := loadTestDatabase()
+	jfk,dus := tdb.AirportByIATA("JFK"),tdb.AirportByIATA("DUS")
+	lat,long := jfk.MidpointTo(dus)
+	d1 := Haversine(jfk.Lat,jfk.Long,lat,long)
+	d2 := Haversine(lat,long,dus.Lat,dus.Long)
+	if math.Abs(d1 - d2) > 1e-6 {
+		t.Errorf("Midpoint is not equidistant: %f != %f",d1,d2)
+	}
+	// The great-circle path bulges north of both endpoints.
+	if lat < dus.Lat {
+		t.Errorf("Midpoint latitude %f is expected north of DUS.",lat)
+	}
+	if lat,long = jfk.PointAlong(dus,1); math.Abs(lat - dus.Lat) > 1e-9 || math.Abs(long - dus.Long) > 1e-9 {
+		t.Errorf("Point at fraction 1 must be the destination: %f,%f",lat,long)
+	}
+}
+
+func TestArcPoints(t *testing.T) {
+	tdb := loadTestDatabase()
+	find := func(src, dst string) *RouteRecord {
+		return tdb.FindRoute(func(r *RouteRecord) bool { return r.SourceAirport == src && r.DestAirport == dst })
+	}
+	jfk,lhr := find("JFK","LHR"),find("LHR","JFK")
+	points := jfk.ArcPoints(16)
+	if len(points) != 17 {
+		t.Fatalf("Expected 17 points but got %d",len(points))
+	}
+	s,d := jfk.SourceAirportP,jfk.DestAirportP
+	if points[0] != [2]float64{s.Long,s.Lat} || points[16] != [2]float64{d.Long,d.Lat} {
+		t.Errorf("Unexpected endpoints: %v/%v",points[0],points[16])
+	}
+	// The great-circle path from JFK to LHR bends towards the north.
+	if points[8][1] <= math.Max(s.Lat,d.Lat) {
+		t.Errorf("Expected the midpoint north of both airports but got %v",points[8])
+	}
+	if lines := lhr.ArcLines(16); len(lines) != 1 || len(lines[0]) != 17 {
+		t.Errorf("Expected a single polyline from LHR to JFK.")
+	}
+
+	// SYD -> LAX crosses the antimeridian.
+	lines := find("SYD","LAX").ArcLines(32)
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 polylines but got %d",len(lines))
+	}
+	a,b := lines[0][len(lines[0]) - 1],lines[1][0]
+	if a[0] != 180 || b[0] != -180 || a[1] != b[1] || len(lines[0]) + len(lines[1]) != 35 {
+		t.Errorf("Unexpected split at the antimeridian: %v/%v",a,b)
+	}
+	for _,line := range lines {
+		for i := 1; i < len(line); i++ {
+			if math.Abs(line[i][0] - line[i - 1][0]) > 180 {
+				t.Errorf("Polyline must not wrap around: %v",line)
+			}
+		}
+	}
+	if (&RouteRecord{}).ArcPoints(8) != nil || (&RouteRecord{}).ArcLines(8) != nil {
+		t.Errorf("Expected nil for unresolved routes.")
+	}
+}
+
+func TestDistanceMatrix(t *testing.T) {
+	tdb := loadTestDatabase()
+	ids := []int{340,3797,507,340}
+	m,err := tdb.DistanceMatrix(ids)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s",err)
+	}
+	if len(m) != 4 {
+		t.Fatalf("Expected a 4x4 matrix but got %d rows",len(m))
+	}
+	for i := range m {
+		for j := range m[i] {
+			expected := tdb.Airport(ids[i]).DistanceTo(tdb.Airport(ids[j]))
+			if m[i][j] != m[j][i] || math.Abs(m[i][j] - expected) > 1e-9 {
+				t.Errorf("Unexpected distance [%d][%d]: %f",i,j,m[i][j])
+			}
+		}
+	}
+	if m[1][1] != 0 || m[0][3] != 0 || m[0][1] < 6000 {
+		t.Errorf("Unexpected distances: %v",m)
+	}
+	if _,err = tdb.DistanceMatrix([]int{340,123456}); err == nil {
+		t.Errorf("Expected an error for an unknown airport.")
+	}
+	if m,err = tdb.DistanceMatrix(nil); err != nil || len(m) != 0 {
+		t.Errorf("Expected an empty matrix.")
+	}
+}
@@ -0,0 +1,81 @@
+package gopenflights
+
+import(
+	"encoding/json"
+)
+
+// geoJSONGeometry is a GeoJSON geometry object.
+type geoJSONGeometry struct {
+	Type string `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// geoJSONFeature is a GeoJSON feature object.
+type geoJSONFeature struct {
+	Type string `json:"type"`
+	Geometry geoJSONGeometry `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// geoJSONFeatureCollection is a GeoJSON feature collection object.
+type geoJSONFeatureCollection struct {
+	Type string `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+// AirportsGeoJSON returns all airports as GeoJSON FeatureCollection of Point features.
+// In contrast to AirportsGeo, coordinates are given as [longitude, latitude] as
+// required by the GeoJSON specification. Each feature carries the name, IATA code,
+// city, country and number of routes of the airport as properties.
+func (d *Database) AirportsGeoJSON() ([]byte, error) {
+	fc := geoJSONFeatureCollection{Type: "FeatureCollection", Features: make([]geoJSONFeature,0,len(d.Airports))}
+	for i := range d.Airports {
+		a := &d.Airports[i]
+		fc.Features = append(fc.Features,geoJSONFeature{
+			Type: "Feature",
+			Geometry: geoJSONGeometry{"Point",[]float64{a.Long,a.Lat}},
+			Properties: map[string]interface{}{
+				"name": a.Name,
+				"iata": a.IATA,
+				"city": a.City,
+				"country": a.Country,
+				"routes": len(a.DestRoutes) + len(a.SourceRoutes),
+			},
+		})
+	}
+	return json.Marshal(fc)
+}
+
+// RoutesGeoJSON returns all routes as GeoJSON FeatureCollection of LineString features.
+// Like RoutesGeo, routes between the same airports are included only once regardless
+// of their direction and airline; the properties (airline, equipment and stops) are
+// taken from the first of them. Routes with unresolved airports are skipped.
+// Coordinates are given as [longitude, latitude].
+func (d *Database) RoutesGeoJSON() ([]byte, error) {
+	type pair struct {
+		a,b *AirportRecord
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	fc := geoJSONFeatureCollection{Type: "FeatureCollection", Features: []geoJSONFeature{}}
+	done := make(map[pair]bool)
+	for i := range d.Routes {
+		r := &d.Routes[i]
+		s,t := r.SourceAirportP,r.DestAirportP
+		if s == nil || t == nil || done[pair{s,t}] {
+			continue
+		}
+		done[pair{s,t}] = true
+		done[pair{t,s}] = true
+		fc.Features = append(fc.Features,geoJSONFeature{
+			Type: "Feature",
+			Geometry: geoJSONGeometry{"LineString",[][]float64{{s.Long,s.Lat},{t.Long,t.Lat}}},
+			Properties: map[string]interface{}{
+				"airline": r.AirlineCode(),
+				"equipment": r.Equipment,
+				"stops": r.Stops,
+			},
+		})
+	}
+	return json.Marshal(fc)
+}
@@ -0,0 +1,72 @@
+package gopenflights
+
+import(
+	"encoding/json"
+	"testing"
+)
+
+func TestAirportsGeoJSON(t *testing.T) {
+	tdb := loadTestDatabase()
+	data,err := tdb.AirportsGeoJSON()
+	if err != nil {
+		t.Fatalf("Could not create GeoJSON: %s",err)
+	}
+	var fc struct {
+		Type string
+		Features []struct {
+			Geometry struct {
+				Type string
+				Coordinates []float64
+			}
+			Properties map[string]interface{}
+		}
+	}
+	if err = json.Unmarshal(data,&fc); err != nil {
+		t.Fatalf("Invalid GeoJSON: %s",err)
+	}
+	if fc.Type != "FeatureCollection" || len(fc.Features) != len(tdb.Airports) {
+		t.Fatalf("Unexpected feature collection: %s with %d features",fc.Type,len(fc.Features))
+	}
+	for _,f := range fc.Features {
+		if f.Properties["iata"] == "SYD" {
+			if f.Geometry.Type != "Point" || f.Geometry.Coordinates[0] < 0 || f.Geometry.Coordinates[1] > 0 {
+				t.Errorf("Sydney must be located at a positive longitude and negative latitude: %v",f.Geometry.Coordinates)
+			}
+			if f.Properties["routes"] != 2.0 {
+				t.Errorf("Unexpected route count of Sydney: %v",f.Properties["routes"])
+			}
+		}
+	}
+}
+
+func TestRoutesGeoJSON(t *testing.T) {
+	tdb := loadTestDatabase()
+	data,err := tdb.RoutesGeoJSON()
+	if err != nil {
+		t.Fatalf("Could not create GeoJSON: %s",err)
+	}
+	var fc struct {
+		Features []struct {
+			Geometry struct {
+				Type string
+				Coordinates [][]float64
+			}
+			Properties map[string]interface{}
+		}
+	}
+	if err = json.Unmarshal(data,&fc); err != nil {
+		t.Fatalf("Invalid GeoJSON: %s",err)
+	}
+	// JFK-DUS, JFK-LHR, LHR-FRA, FRA-JFK, FRA-DUS, FRA-HND, HND-LAX, LAX-JFK, SYD-LAX, LHR-DUS
+	if len(fc.Features) != 10 {
+		t.Errorf("Expected 10 distinct routes but got %d",len(fc.Features))
+	}
+	for _,f := range fc.Features {
+		if f.Geometry.Type != "LineString" || len(f.Geometry.Coordinates) != 2 {
+			t.Errorf("Unexpected geometry: %v",f.Geometry)
+		}
+		if f.Properties["airline"] == "QF" && f.Geometry.Coordinates[0][1] > 0 {
+			t.Errorf("Sydney must be located at a negative latitude.")
+		}
+	}
+}
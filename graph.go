@@ -0,0 +1,294 @@
+package gopenflights
+
+import(
+	"container/heap"
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// legsTo reconstructs the legs leading to the given airport id from the given
+// map of incoming routes.
+func legsTo(aid int, via map[int]*RouteRecord) (ret []*RouteRecord) {
+	for r := via[aid]; r != nil; r = via[r.SourceAirportId] {
+		ret = append([]*RouteRecord{r},ret...)
+	}
+	return
+}
+
+// ShortestPath returns the legs of an itinerary with the fewest number of legs
+// from the source to the destination airport id. Each route is treated as a
+// directed connection from its source to its destination airport.
+// An error is returned if one of the airports is unknown or there is no connection.
+func (d *Database) ShortestPath(srcId, dstId int) ([]*RouteRecord, error) {
+	src := d.Airport(srcId)
+	if src == nil {
+		return nil,fmt.Errorf("Unknown source airportId: %d",srcId)
+	}
+	if d.Airport(dstId) == nil {
+		return nil,fmt.Errorf("Unknown destination airportId: %d",dstId)
+	}
+	if srcId == dstId {
+		return []*RouteRecord{},nil
+	}
+
+	via := make(map[int]*RouteRecord)
+	visited := map[int]bool{srcId: true}
+	queue := []*AirportRecord{src}
+	for len(queue) > 0 {
+		ap := queue[0]
+		queue = queue[1:]
+		for _,r := range keys(ap.SourceRoutes) {
+			next := r.DestAirportP
+			if next == nil || visited[next.Id] {
+				continue
+			}
+			visited[next.Id] = true
+			via[next.Id] = r
+			if next.Id == dstId {
+				return legsTo(dstId,via),nil
+			}
+			queue = append(queue,next)
+		}
+	}
+	return nil,fmt.Errorf("No connection from airportId %d to %d.",srcId,dstId)
+}
+
+// distItem is an entry of a distQueue.
+type distItem struct {
+	airport *AirportRecord
+	dist float64
+}
+
+// distQueue is a priority queue of airports ordered by ascending distance.
+type distQueue []distItem
+
+func (q distQueue) Len() int { return len(q) }
+func (q distQueue) Less(i, j int) bool { return q[i].dist < q[j].dist }
+func (q distQueue) Swap(i, j int) { q[i],q[j] = q[j],q[i] }
+func (q *distQueue) Push(x interface{}) { *q = append(*q,x.(distItem)) }
+func (q *distQueue) Pop() interface{} {
+	old := *q
+	it := old[len(old) - 1]
+	*q = old[:len(old) - 1]
+	return it
+}
+
+// ShortestDistancePath returns the legs of the itinerary with the smallest total
+// great-circle distance from the source to the destination airport id together
+// with its total distance in km.
+// An error is returned if one of the airports is unknown or there is no connection.
+func (d *Database) ShortestDistancePath(srcId, dstId int) ([]*RouteRecord, float64, error) {
+	src := d.Airport(srcId)
+	if src == nil {
+		return nil,0,fmt.Errorf("Unknown source airportId: %d",srcId)
+	}
+	if d.Airport(dstId) == nil {
+		return nil,0,fmt.Errorf("Unknown destination airportId: %d",dstId)
+	}
+
+	via := make(map[int]*RouteRecord)
+	dist := map[int]float64{srcId: 0}
+	done := make(map[int]bool)
+	q := &distQueue{{src,0}}
+	for q.Len() > 0 {
+		it := heap.Pop(q).(distItem)
+		ap := it.airport
+		if done[ap.Id] {
+			continue
+		}
+		done[ap.Id] = true
+		if ap.Id == dstId {
+			return legsTo(dstId,via),it.dist,nil
+		}
+		for _,r := range keys(ap.SourceRoutes) {
+			next := r.DestAirportP
+			if next == nil || done[next.Id] {
+				continue
+			}
+			leg,err := r.Distance()
+			if err != nil {
+				continue
+			}
+			if nd,ok := dist[next.Id]; !ok || it.dist + leg < nd {
+				dist[next.Id] = it.dist + leg
+				via[next.Id] = r
+				heap.Push(q,distItem{next,it.dist + leg})
+			}
+		}
+	}
+	return nil,0,fmt.Errorf("No connection from airportId %d to %d.",srcId,dstId)
+}
+
+// PathsWithMaxHops returns all distinct itineraries from the source to the destination
+// airport id with at most maxHops legs. Itineraries visiting an airport more than once
+// are not considered.
+func (d *Database) PathsWithMaxHops(srcId, dstId, maxHops int) (ret [][]*RouteRecord) {
+	src := d.Airport(srcId)
+	if src == nil || maxHops < 1 {
+		return
+	}
+	visited := map[int]bool{srcId: true}
+	var legs []*RouteRecord
+	var walk func(ap *AirportRecord)
+	walk = func(ap *AirportRecord) {
+		for _,r := range keys(ap.SourceRoutes) {
+			next := r.DestAirportP
+			if next == nil || visited[next.Id] {
+				continue
+			}
+			legs = append(legs,r)
+			if next.Id == dstId {
+				ret = append(ret,append([]*RouteRecord{},legs...))
+			} else if len(legs) < maxHops {
+				visited[next.Id] = true
+				walk(next)
+				visited[next.Id] = false
+			}
+			legs = legs[:len(legs) - 1]
+		}
+	}
+	walk(src)
+	return
+}
+
+// AdjacencyList returns the route network as directed graph. Each airport id is mapped
+// to the sorted ids of all airports that are directly reachable from it. Destinations
+// served by several routes are listed once. Airports without departing routes are
+// mapped to an empty list. Routes with unresolved destination airports are skipped.
+func (d *Database) AdjacencyList() map[int][]int {
+	ret := make(map[int][]int,len(d.Airports))
+	for i := range d.Airports {
+		ap := &d.Airports[i]
+		seen := make(map[int]bool)
+		dsts := []int{}
+		for r := range ap.SourceRoutes {
+			if next := r.DestAirportP; next != nil && !seen[next.Id] {
+				seen[next.Id] = true
+				dsts = append(dsts,next.Id)
+			}
+		}
+		sort.Ints(dsts)
+		ret[ap.Id] = dsts
+	}
+	return ret
+}
+
+// Reachable returns all airports that can be reached from the source airport id with
+// at most maxHops legs mapped to the minimum number of legs required. The source
+// airport itself is not included. An empty map is returned for unknown airports.
+func (d *Database) Reachable(srcId, maxHops int) map[int]int {
+	ret := make(map[int]int)
+	src := d.Airport(srcId)
+	if src == nil {
+		return ret
+	}
+	frontier := []*AirportRecord{src}
+	for hops := 1; hops <= maxHops && len(frontier) > 0; hops++ {
+		var next []*AirportRecord
+		for _,ap := range frontier {
+			for r := range ap.SourceRoutes {
+				dst := r.DestAirportP
+				if dst == nil || dst.Id == srcId {
+					continue
+				}
+				if _,ok := ret[dst.Id]; !ok {
+					ret[dst.Id] = hops
+					next = append(next,dst)
+				}
+			}
+		}
+		frontier = next
+	}
+	return ret
+}
+
+// BetweennessCentrality returns the betweenness centrality of all airports on the
+// directed route network of AdjacencyList. The centrality of an airport is the sum of
+// the fractions of shortest paths (by number of legs) between all other pairs of airports
+// that pass through it. Values are not normalized. Hubs have the highest values.
+// The exact computation visits all airports and may be slow for the full dataset.
+// See ApproxBetweennessCentrality.
+func (d *Database) BetweennessCentrality() map[int]float64 {
+	adj := d.AdjacencyList()
+	ids := sortedIds(adj)
+	ret := make(map[int]float64,len(adj))
+	for _,id := range ids {
+		ret[id] = 0
+	}
+	for _,id := range ids {
+		accumulateBetweenness(adj,id,ret)
+	}
+	return ret
+}
+
+// ApproxBetweennessCentrality returns an approximation of BetweennessCentrality which
+// only considers the shortest paths starting at the given number of randomly sampled
+// airports. The results are scaled to the total number of airports and are therefore
+// only estimates of the exact values. The given seed makes the sampling reproducible.
+// If samples is not less than the number of airports, the exact values are returned.
+func (d *Database) ApproxBetweennessCentrality(samples int, seed int64) map[int]float64 {
+	adj := d.AdjacencyList()
+	if samples >= len(adj) {
+		return d.BetweennessCentrality()
+	}
+	ids := sortedIds(adj)
+	ret := make(map[int]float64,len(adj))
+	for _,id := range ids {
+		ret[id] = 0
+	}
+	if samples < 1 {
+		return ret
+	}
+	rnd := rand.New(rand.NewSource(seed))
+	for _,i := range rnd.Perm(len(ids))[:samples] {
+		accumulateBetweenness(adj,ids[i],ret)
+	}
+	scale := float64(len(ids)) / float64(samples)
+	for id := range ret {
+		ret[id] *= scale
+	}
+	return ret
+}
+
+// sortedIds returns the sorted airport ids of the given adjacency list.
+func sortedIds(adj map[int][]int) []int {
+	ret := make([]int,0,len(adj))
+	for id := range adj {
+		ret = append(ret,id)
+	}
+	sort.Ints(ret)
+	return ret
+}
+
+// accumulateBetweenness adds the dependencies of all airports on the shortest paths
+// starting at the given source airport id to ret using the algorithm of Brandes.
+func accumulateBetweenness(adj map[int][]int, src int, ret map[int]float64) {
+	dist := map[int]int{src: 0}
+	sigma := map[int]float64{src: 1}
+	pred := make(map[int][]int)
+	var order []int
+	for queue := []int{src}; len(queue) > 0; {
+		v := queue[0]
+		queue = queue[1:]
+		order = append(order,v)
+		for _,w := range adj[v] {
+			if _,ok := dist[w]; !ok {
+				dist[w] = dist[v] + 1
+				queue = append(queue,w)
+			}
+			if dist[w] == dist[v] + 1 {
+				sigma[w] += sigma[v]
+				pred[w] = append(pred[w],v)
+			}
+		}
+	}
+	delta := make(map[int]float64,len(order))
+	for i := len(order) - 1; i > 0; i-- {
+		w := order[i]
+		for _,v := range pred[w] {
+			delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+		}
+		ret[w] += delta[w]
+	}
+}
@@ -0,0 +1,173 @@
+package gopenflights
+
+import(
+	"reflect"
+	"testing"
+)
+
+// checkPath verifies that the given legs form a connected itinerary from src to dst.
+func checkPath(t *testing.T, legs []*RouteRecord, src, dst int) {
+	if len(legs) == 0 {
+		t.Fatalf("Empty itinerary from %d to %d.",src,dst)
+	}
+	at := src
+	for _,r := range legs {
+		if r.SourceAirportId != at {
+			t.Errorf("Leg %s -> %s does not start at airportId %d",r.SourceAirport,r.DestAirport,at)
+		}
+		at = r.DestAirportId
+	}
+	if at != dst {
+		t.Errorf("Itinerary ends at airportId %d instead of %d",at,dst)
+	}
+}
+
+func TestShortestPath(t *testing.T) {
+	tdb := loadTestDatabase()
+	// SYD -> LAX -> JFK -> DUS
+	legs,err := tdb.ShortestPath(3361,345)
+	if err != nil {
+		t.Fatalf("Could not find path: %s",err)
+	}
+	checkPath(t,legs,3361,345)
+	if len(legs) != 3 {
+		t.Errorf("Expected 3 legs but got %d",len(legs))
+	}
+
+	// Goroka has no routes at all.
+	if _,err = tdb.ShortestPath(3361,1); err == nil {
+		t.Errorf("Expected an error for a disconnected airport.")
+	}
+	if _,err = tdb.ShortestPath(3361,123456); err == nil {
+		t.Errorf("Expected an error for an unknown airport.")
+	}
+}
+
+func TestShortestDistancePath(t *testing.T) {
+	tdb := loadTestDatabase()
+	// HND -> FRA -> DUS is shorter than HND -> LAX -> JFK -> DUS
+	legs,dist,err := tdb.ShortestDistancePath(2359,345)
+	if err != nil {
+		t.Fatalf("Could not find path: %s",err)
+	}
+	checkPath(t,legs,2359,345)
+	if len(legs) != 2 || legs[0].DestAirport != "FRA" {
+		t.Errorf("Expected the itinerary via FRA.")
+	}
+	sum := 0.0
+	for _,r := range legs {
+		d,_ := r.Distance()
+		sum += d
+	}
+	if sum != dist {
+		t.Errorf("Total distance %f does not match the sum of legs %f",dist,sum)
+	}
+
+	if _,_,err = tdb.ShortestDistancePath(2359,1); err == nil {
+		t.Errorf("Expected an error for a disconnected airport.")
+	}
+}
+
+func TestPathsWithMaxHops(t *testing.T) {
+	tdb := loadTestDatabase()
+	// Direct flights from LHR to JFK are operated by AA and BA.
+	if paths := tdb.PathsWithMaxHops(507,3797,1); len(paths) != 2 {
+		t.Errorf("Expected 2 direct routes but got %d",len(paths))
+	}
+
+	// LHR -> FRA -> JFK and LHR -> DUS -> JFK in addition.
+	paths := tdb.PathsWithMaxHops(507,3797,2)
+	if len(paths) != 4 {
+		t.Errorf("Expected 4 itineraries but got %d",len(paths))
+	}
+	for _,p := range paths {
+		checkPath(t,p,507,3797)
+		if len(p) > 2 {
+			t.Errorf("Itinerary exceeds maximum number of legs: %d",len(p))
+		}
+	}
+}
+
+func TestAdjacencyList(t *testing.T) {
+	tdb := loadTestDatabase()
+	adj := tdb.AdjacencyList()
+	if len(adj) != len(tdb.Airports) {
+		t.Errorf("Expected %d airports but got %d",len(tdb.Airports),len(adj))
+	}
+	// LHR -> JFK is served by AA and BA but listed once.
+	if lhr := adj[507]; len(lhr) != 3 || lhr[0] != 340 || lhr[1] != 345 || lhr[2] != 3797 {
+		t.Errorf("Unexpected destinations of LHR: %v",lhr)
+	}
+	// FRA -> QQQ has an unknown destination.
+	for _,id := range adj[340] {
+		if tdb.Airport(id) == nil {
+			t.Errorf("Unknown destination %d of FRA",id)
+		}
+	}
+	if gka := adj[1]; gka == nil || len(gka) != 0 {
+		t.Errorf("Expected no destinations of GKA but got %v",gka)
+	}
+}
+
+func TestReachable(t *testing.T) {
+	tdb := loadTestDatabase()
+	direct := tdb.Reachable(507,1)
+	adj := tdb.AdjacencyList()[507]
+	if len(direct) != len(adj) {
+		t.Errorf("Expected the direct destinations of LHR %v but got %v",adj,direct)
+	}
+	for _,id := range adj {
+		if direct[id] != 1 {
+			t.Errorf("Expected airport %d to be reached with 1 hop but got %d",id,direct[id])
+		}
+	}
+	// SYD can be reached via JFK and LAX.
+	all := tdb.Reachable(507,3)
+	if all[3361] != 3 || all[3484] != 2 || all[3797] != 1 {
+		t.Errorf("Unexpected hop counts: %v",all)
+	}
+	if _,ok := all[507]; ok {
+		t.Errorf("Source airport must not be included.")
+	}
+	if len(tdb.Reachable(1,5)) != 0 || len(tdb.Reachable(42,5)) != 0 {
+		t.Errorf("Expected nothing to be reachable from GKA and unknown airports.")
+	}
+}
+
+func TestBetweennessCentrality(t *testing.T) {
+	tdb := loadTestDatabase()
+	bc := tdb.BetweennessCentrality()
+	if len(bc) != len(tdb.Airports) {
+		t.Errorf("Expected %d airports but got %d",len(tdb.Airports),len(bc))
+	}
+	// JFK connects the european airports with LAX and SYD.
+	for id,v := range bc {
+		if id != 3797 && v >= bc[3797] {
+			t.Errorf("Expected JFK to be the main hub but airport %d has %f >= %f",id,v,bc[3797])
+		}
+	}
+	if bc[1] != 0 || bc[3361] != 0 {
+		t.Errorf("Expected no betweenness of GKA and SYD: %v",bc)
+	}
+	if !reflect.DeepEqual(tdb.ApproxBetweennessCentrality(len(tdb.Airports),1),bc) {
+		t.Errorf("Expected exact values if all airports are sampled.")
+	}
+	approx := tdb.ApproxBetweennessCentrality(4,1)
+	if len(approx) != len(bc) || !reflect.DeepEqual(approx,tdb.ApproxBetweennessCentrality(4,1)) {
+		t.Errorf("Expected reproducible approximations: %v",approx)
+	}
+
+	// A -> C is connected via B and via D.
+	small := new(Database)
+	small.Airports = []AirportRecord{{Id: 1},{Id: 2},{Id: 3},{Id: 4}}
+	small.Routes = []RouteRecord{
+		{SourceAirportId: 1, DestAirportId: 2},
+		{SourceAirportId: 2, DestAirportId: 3},
+		{SourceAirportId: 1, DestAirportId: 4},
+		{SourceAirportId: 4, DestAirportId: 3},
+	}
+	small.Reindex()
+	if bc = small.BetweennessCentrality(); !reflect.DeepEqual(bc,map[int]float64{1: 0, 2: 0.5, 3: 0, 4: 0.5}) {
+		t.Errorf("Unexpected betweenness: %v",bc)
+	}
+}
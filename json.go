@@ -0,0 +1,46 @@
+package gopenflights
+
+import(
+	"encoding/json"
+)
+
+// jsonDatabase is the JSON representation of a Database.
+type jsonDatabase struct {
+	Airports []AirportRecord `json:"airports"`
+	Airlines []AirlineRecord `json:"airlines"`
+	Routes []RouteRecord `json:"routes"`
+	Countries []CountryRecord `json:"countries,omitempty"`
+	Planes []PlaneRecord `json:"planes,omitempty"`
+}
+
+// MarshalJSON returns the JSON encoding of all airport, airline, route, country and plane records.
+// Indexes and references between the records are not encoded.
+func (d *Database) MarshalJSON() ([]byte, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return json.Marshal(jsonDatabase{d.Airports,d.Airlines,d.Routes,d.Countries,d.Planes})
+}
+
+// UnmarshalJSON replaces all records by the ones encoded by MarshalJSON.
+// All indexes as well as the airport, airline and route references are rebuilt.
+// The sources of the load report are left empty.
+func (d *Database) UnmarshalJSON(data []byte) error {
+	var jd jsonDatabase
+	if err := json.Unmarshal(data,&jd); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.report = LoadReport{}
+	d.warnings = nil
+	d.airportSchema = 0
+	d.Airports,d.Airlines,d.Routes = jd.Airports,jd.Airlines,jd.Routes
+	d.Countries,d.Planes = jd.Countries,jd.Planes
+	d.reindex()
+	d.report.Airports = FileReport{"",len(d.Airports),0}
+	d.report.Airlines = FileReport{"",len(d.Airlines),0}
+	d.report.Routes = FileReport{"",len(d.Routes),0}
+	d.report.Countries = FileReport{"",len(d.Countries),0}
+	d.report.Planes = FileReport{"",len(d.Planes),0}
+	return nil
+}
@@ -0,0 +1,43 @@
+package gopenflights
+
+import(
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	tdb := loadTestDatabase()
+	if err := tdb.LoadCountryData("testdata/countries.dat"); err != nil {
+		t.Fatal(err)
+	}
+	data,err := json.Marshal(tdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jdb := new(Database)
+	if err = json.Unmarshal(data,jdb); err != nil {
+		t.Fatal(err)
+	}
+	if len(jdb.Airports) != 9 || len(jdb.Airlines) != 8 || len(jdb.Routes) != 20 {
+		t.Fatalf("Unexpected record counts: %d/%d/%d",len(jdb.Airports),len(jdb.Airlines),len(jdb.Routes))
+	}
+
+	lhr := jdb.AirportByIATA("LHR")
+	if lhr == nil || len(lhr.SourceRoutes) != len(tdb.AirportByIATA("LHR").SourceRoutes) {
+		t.Fatalf("Expected the routes of LHR to be relinked.")
+	}
+	for r := range lhr.SourceRoutes {
+		if r.SourceAirportP != lhr {
+			t.Errorf("Route %s -> %s is not linked to LHR.",r.SourceAirport,r.DestAirport)
+		}
+	}
+	if al := jdb.AirlineByIATA("BA"); al == nil || len(al.Routes) != len(tdb.AirlineByIATA("BA").Routes) {
+		t.Errorf("Expected the routes of BA to be relinked.")
+	}
+	if c := jdb.CountryByISO("DE"); c == nil || c.Name != "Germany" {
+		t.Errorf("Expected the countries to be reindexed.")
+	}
+	if rs := jdb.RoutesBetween(507,3797); len(rs) != 2 {
+		t.Errorf("Expected 2 routes from LHR to JFK but got %d",len(rs))
+	}
+}
@@ -0,0 +1,162 @@
+package gopenflights
+
+import(
+	"math"
+	"sort"
+)
+
+// kdNode is a node of a kdTree.
+type kdNode struct {
+	airport *AirportRecord
+	p vec3
+	axis int
+	left,right *kdNode
+}
+
+// kdTree is a 3-dimensional k-d tree over the unit vectors of airport coordinates.
+// The euclidean (chord) distance between unit vectors grows monotonically with the
+// great-circle distance, so nearest neighbours in the tree are nearest airports.
+type kdTree struct {
+	root *kdNode
+}
+
+// kdCandidate is an airport found during a tree search together with its squared chord distance.
+type kdCandidate struct {
+	airport *AirportRecord
+	dist float64
+}
+
+// newKdTree builds a kdTree over all airports with valid coordinates.
+func newKdTree(airports []AirportRecord) *kdTree {
+	nodes := make([]*kdNode,0,len(airports))
+	for i := range airports {
+		if a := &airports[i]; a.hasPosition() {
+			nodes = append(nodes,&kdNode{airport: a, p: unitVector(a.Lat,a.Long)})
+		}
+	}
+	return &kdTree{buildKdNodes(nodes,0)}
+}
+
+// buildKdNodes recursively builds a balanced subtree of the given nodes.
+func buildKdNodes(nodes []*kdNode, depth int) *kdNode {
+	if len(nodes) == 0 {
+		return nil
+	}
+	axis := depth % 3
+	sort.Slice(nodes,func(i,j int) bool { return nodes[i].p[axis] < nodes[j].p[axis] })
+	m := len(nodes) / 2
+	n := nodes[m]
+	n.axis = axis
+	n.left = buildKdNodes(nodes[:m],depth + 1)
+	n.right = buildKdNodes(nodes[m + 1:],depth + 1)
+	return n
+}
+
+// chord2 returns the squared chord distance of the two unit vectors.
+func chord2(a, b vec3) float64 {
+	d := vec3{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+	return d.dot(d)
+}
+
+// nearest returns the k nodes closest to the target sorted by ascending distance.
+func (t *kdTree) nearest(target vec3, k int) (ret []kdCandidate) {
+	if k <= 0 {
+		return
+	}
+	var search func(n *kdNode)
+	search = func(n *kdNode) {
+		if n == nil {
+			return
+		}
+		d := chord2(target,n.p)
+		if len(ret) < k || d < ret[len(ret) - 1].dist {
+			i := sort.Search(len(ret),func(i int) bool { return ret[i].dist > d })
+			if len(ret) < k {
+				ret = append(ret,kdCandidate{})
+			}
+			copy(ret[i + 1:],ret[i:])
+			ret[i] = kdCandidate{n.airport,d}
+		}
+		diff := target[n.axis] - n.p[n.axis]
+		near,far := n.left,n.right
+		if diff > 0 {
+			near,far = far,near
+		}
+		search(near)
+		if len(ret) < k || diff * diff < ret[len(ret) - 1].dist {
+			search(far)
+		}
+	}
+	search(t.root)
+	return
+}
+
+// tree returns the spatial airport index and builds it if required.
+func (d *Database) tree() *kdTree {
+	d.treeMu.Lock()
+	defer d.treeMu.Unlock()
+	if d.airportTree == nil {
+		d.airportTree = newKdTree(d.Airports)
+	}
+	return d.airportTree
+}
+
+// resetTree drops the spatial airport index.
+func (d *Database) resetTree() {
+	d.treeMu.Lock()
+	defer d.treeMu.Unlock()
+	d.airportTree = nil
+}
+
+// NearestAirports returns the k airports closest to the given coordinate sorted by
+// ascending distance. If there are less than k airports, all airports are returned.
+// Airports without valid coordinates are skipped.
+// The spatial index used for the lookup is built on the first call.
+func (d *Database) NearestAirports(lat, long float64, k int) (ret []*AirportRecord) {
+	for _,c := range d.tree().nearest(unitVector(lat,long),k) {
+		ret = append(ret,c.airport)
+	}
+	return
+}
+
+// within returns all nodes with a squared chord distance of at most max2 to the
+// target sorted by ascending distance.
+func (t *kdTree) within(target vec3, max2 float64) (ret []kdCandidate) {
+	var search func(n *kdNode)
+	search = func(n *kdNode) {
+		if n == nil {
+			return
+		}
+		if d := chord2(target,n.p); d <= max2 {
+			ret = append(ret,kdCandidate{n.airport,d})
+		}
+		diff := target[n.axis] - n.p[n.axis]
+		if diff <= 0 || diff * diff <= max2 {
+			search(n.left)
+		}
+		if diff >= 0 || diff * diff <= max2 {
+			search(n.right)
+		}
+	}
+	search(t.root)
+	sort.Slice(ret,func(i,j int) bool { return ret[i].dist < ret[j].dist })
+	return
+}
+
+// AirportsWithinRadius returns all airports within the given great-circle radius in km
+// around the given coordinate sorted by ascending distance.
+// Airports without valid coordinates are skipped.
+func (d *Database) AirportsWithinRadius(lat, long, radiusKm float64) (ret []*AirportRecord) {
+	if radiusKm < 0 {
+		return
+	}
+	// Convert the great-circle radius into the chord length on the unit sphere.
+	c := 2.0
+	if radiusKm < math.Pi * EarthRadius {
+		c = 2 * math.Sin(radiusKm / (2 * EarthRadius))
+	}
+	for _,cand := range d.tree().within(unitVector(lat,long),c * c) {
+		ret = append(ret,cand.airport)
+	}
+	return
+}
@@ -0,0 +1,312 @@
+package gopenflights
+
+import(
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// config holds the settings of a Database created by Open.
+type config struct {
+	airports,airlines,routes,countries,planes string
+	baseURL string
+	cacheDir string
+	cacheTTL time.Duration
+	client *http.Client
+	logger Logger
+	airportTypes []string
+	comma rune
+	unknownAirlines UnknownAirlinePolicy
+	attempts int
+	retryDelay time.Duration
+}
+
+// Option configures a Database created by Open.
+type Option func(*config)
+
+// WithAirportsFile sets the local "airports.dat" file to load the airports from.
+func WithAirportsFile(path string) Option {
+	return func(c *config) { c.airports = path }
+}
+
+// WithAirportsURL sets the http based URL to load the airports from.
+func WithAirportsURL(url string) Option {
+	return func(c *config) { c.airports = url }
+}
+
+// WithAirlinesFile sets the local "airlines.dat" file to load the airlines from.
+func WithAirlinesFile(path string) Option {
+	return func(c *config) { c.airlines = path }
+}
+
+// WithAirlinesURL sets the http based URL to load the airlines from.
+func WithAirlinesURL(url string) Option {
+	return func(c *config) { c.airlines = url }
+}
+
+// WithRoutesFile sets the local "routes.dat" file to load the routes from.
+func WithRoutesFile(path string) Option {
+	return func(c *config) { c.routes = path }
+}
+
+// WithRoutesURL sets the http based URL to load the routes from.
+func WithRoutesURL(url string) Option {
+	return func(c *config) { c.routes = url }
+}
+
+// WithCountriesFile sets the local "countries.dat" file to load the countries from.
+// Countries are only loaded if explicitly configured.
+func WithCountriesFile(path string) Option {
+	return func(c *config) { c.countries = path }
+}
+
+// WithCountriesURL sets the http based URL to load the countries from.
+// Countries are only loaded if explicitly configured.
+func WithCountriesURL(url string) Option {
+	return func(c *config) { c.countries = url }
+}
+
+// WithPlanesFile sets the local "planes.dat" file to load the aircraft types from.
+// Planes are only loaded if explicitly configured.
+func WithPlanesFile(path string) Option {
+	return func(c *config) { c.planes = path }
+}
+
+// WithPlanesURL sets the http based URL to load the aircraft types from.
+// Planes are only loaded if explicitly configured.
+func WithPlanesURL(url string) Option {
+	return func(c *config) { c.planes = url }
+}
+
+// WithBaseURL sets the base URL the default sources are downloaded from. The file names
+// like DefaultAirportsFilename are appended to it. By default DefaultBaseDatUrl and the
+// Default*DatUrl variables are used.
+func WithBaseURL(url string) Option {
+	return func(c *config) { c.baseURL = url }
+}
+
+// WithCacheDir sets the directory the default source files are cached in.
+// By default DefaultCacheDir is used. The directory is created if missing.
+func WithCacheDir(dir string) Option {
+	return func(c *config) { c.cacheDir = dir }
+}
+
+// WithCacheTTL sets the maximum age of cached source files. Cached files older than
+// the given duration are downloaded again. By default cached files never expire.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(c *config) { c.cacheTTL = ttl }
+}
+
+// WithHTTPClient sets the http client used for all downloads.
+// By default DefaultHTTPClient is used.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *config) { c.client = client }
+}
+
+// WithRetry retries failed downloads of the default sources and of http sources like
+// the one given to WithAirportsURL until the given number of attempts has been made.
+// The delay before the first retry is baseDelay and doubles with every further attempt.
+// Requests answered with a client error status like 404 are not retried. Retries stop
+// once the context of OpenContext or RefreshContext is done or its deadline would pass
+// during the delay. By default each download is attempted once.
+func WithRetry(attempts int, baseDelay time.Duration) Option {
+	return func(c *config) { c.attempts,c.retryDelay = attempts,baseDelay }
+}
+
+// WithLogger sets the logger used for all messages of the database.
+// By default the standard logger of the log package is used. Use NopLogger to turn logging off.
+func WithLogger(logger Logger) Option {
+	return func(c *config) { c.logger = logger }
+}
+
+// WithAirportTypes restricts the loaded airports to the given types like AirportTypeAirport.
+// See Database.SetAirportTypes.
+func WithAirportTypes(types ...string) Option {
+	return func(c *config) { c.airportTypes = types }
+}
+
+// WithUnknownAirlinePolicy sets how routes of airlines missing in the airline data are
+// handled. See Database.SetUnknownAirlinePolicy.
+func WithUnknownAirlinePolicy(policy UnknownAirlinePolicy) Option {
+	return func(c *config) { c.unknownAirlines = policy }
+}
+
+// WithDelimiter sets the field delimiter of the source files like '\t' or ';'.
+// See Database.SetDelimiter.
+func WithDelimiter(comma rune) Option {
+	return func(c *config) { c.comma = comma }
+}
+
+// Open initializes a new openflights database configured by the given options.
+// Sources that are not explicitly configured are loaded from the cache directory.
+// If not cached yet, they are downloaded from the default URLs first.
+func Open(opts ...Option) (*Database, error) {
+	return OpenContext(context.Background(),opts...)
+}
+
+// OpenContext initializes a new openflights database like Open does.
+// All downloads are aborted once the given context is done.
+func OpenContext(ctx context.Context, opts ...Option) (*Database, error) {
+	cfg := &config{cacheDir: DefaultCacheDir}
+	for _,opt := range opts {
+		opt(cfg)
+	}
+	d := &Database{client: cfg.client, logger: cfg.logger, cfg: cfg}
+	d.SetAirportTypes(cfg.airportTypes...)
+	d.SetDelimiter(cfg.comma)
+	d.SetUnknownAirlinePolicy(cfg.unknownAirlines)
+	if err := d.load(ctx,false); err != nil {
+		return nil,err
+	}
+	return d,nil
+}
+
+// Refresh downloads all cached source files again and reloads the whole database.
+// Explicitly configured sources are reloaded without using the cache.
+// The database is replaced at once after all sources have been read.
+// Refresh is only supported for databases created by Open or NewDatabase.
+func (d *Database) Refresh() error {
+	return d.RefreshContext(context.Background())
+}
+
+// RefreshContext refreshes the database like Refresh does.
+// All downloads are aborted once the given context is done.
+func (d *Database) RefreshContext(ctx context.Context) error {
+	if d.cfg == nil {
+		return fmt.Errorf("Database has not been created by Open. Sources are unknown.")
+	}
+	return d.load(ctx,true)
+}
+
+// load loads all data files configured in the database config.
+// If refresh is set, cached files are downloaded again. All files are read before
+// the database is locked and replaced at once.
+func (d *Database) load(ctx context.Context, refresh bool) error {
+	cfg := d.cfg
+	files := []struct {
+		kind,source,filename,url string
+		minFields int
+	}{
+		{"Airport",cfg.airports,DefaultAirportsFilename,defaultURL(DefaultAirportDatUrl,DefaultAirportsFilename),11},
+		{"Airline",cfg.airlines,DefaultAirlinesFilename,defaultURL(DefaultAirlineDatUrl,DefaultAirlinesFilename),8},
+		{"Route",cfg.routes,DefaultRoutesFilename,defaultURL(DefaultRoutesDatUrl,DefaultRoutesFilename),9},
+	}
+	sources := make([]string,len(files))
+	data := make([][][]string,len(files))
+	for i,f := range files {
+		if cfg.baseURL != "" {
+			f.url = strings.TrimSuffix(cfg.baseURL,"/") + "/" + f.filename
+		}
+		source,err := d.cached(ctx,f.source,f.filename,f.url,f.minFields,refresh)
+		if err != nil {
+			return err
+		}
+		d.logf("Loading %s data from \"%s\"",f.kind,source)
+		if data[i],err = d.loadCsvContext(ctx,source); err != nil {
+			return err
+		}
+		sources[i] = source
+	}
+	// Countries and planes are only loaded if configured.
+	optional := []struct {
+		kind,source string
+		set func(string,[][]string)
+		data [][]string
+	}{
+		{"Country",cfg.countries,d.setCountryData,nil},
+		{"Plane",cfg.planes,d.setPlaneData,nil},
+	}
+	for i := range optional {
+		o := &optional[i]
+		if o.source == "" {
+			continue
+		}
+		d.logf("Loading %s data from \"%s\"",o.kind,o.source)
+		var err error
+		if o.data,err = d.loadCsvContext(ctx,o.source); err != nil {
+			return err
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.report = LoadReport{}
+	d.warnings = nil
+	d.setAirportData(sources[0],data[0])
+	d.setAirlineData(sources[1],data[1])
+	d.setRouteData(sources[2],data[2])
+	for _,o := range optional {
+		if o.data != nil {
+			o.set(o.source,o.data)
+		}
+	}
+	return nil
+}
+
+// cached returns the given source if it is specified. Otherwise the path of the
+// file in the cache directory is returned which is downloaded from url if it
+// does not exist yet, is older than the configured cache TTL, is no valid csv file
+// with at least minFields columns or refresh is set. Downloads that are no valid
+// csv file are rejected and do not replace the cached file.
+func (d *Database) cached(ctx context.Context, source, filename, url string, minFields int, refresh bool) (string, error) {
+	if source != "" {
+		return source,nil
+	}
+	if err := os.MkdirAll(d.cfg.cacheDir,0755); err != nil {
+		return "",err
+	}
+	path := filepath.Join(d.cfg.cacheDir,filename)
+	check := func(p string) error { return checkDatFile(p,minFields,d.comma) }
+	fi,err := os.Stat(path)
+	if err == nil && !refresh && d.cfg.cacheTTL > 0 && time.Since(fi.ModTime()) > d.cfg.cacheTTL {
+		err = fmt.Errorf("Cached file \"%s\" is expired.",path)
+	}
+	if err == nil && !refresh {
+		if err = check(path); err != nil {
+			d.logf("Ignoring cached file: %s",err.Error())
+		}
+	}
+	if err != nil || refresh {
+		if err = d.download(ctx,url,path,check); err != nil {
+			return "",err
+		}
+	}
+	return path,nil
+}
+
+// download downloads url to path like downloadFile does and retries failed downloads
+// as configured by WithRetry.
+func (d *Database) download(ctx context.Context, url, path string, check func(string) error) error {
+	return d.retry(ctx,func() error {
+		return downloadFile(ctx,d.httpClient(),url,path,nil,check)
+	})
+}
+
+// retry calls fn until it succeeds or the attempts configured by WithRetry are used up.
+// Databases not created by Open make a single attempt.
+func (d *Database) retry(ctx context.Context, fn func() error) error {
+	attempts,delay := 1,time.Duration(0)
+	if d.cfg != nil {
+		attempts,delay = d.cfg.attempts,d.cfg.retryDelay
+	}
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= attempts || ctx.Err() != nil || !retryable(err) {
+			return err
+		}
+		if deadline,ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+		d.logf("Attempt %d/%d failed, retrying in %s: %s",attempt,attempts,delay,err.Error())
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
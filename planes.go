@@ -0,0 +1,105 @@
+package gopenflights
+
+import(
+	"context"
+	"fmt"
+	"io"
+)
+
+// PlaneRecord represents an aircraft type of the "planes.dat" file.
+type PlaneRecord struct {
+	Name string
+	IATA string
+	ICAO string
+}
+
+// Convert converts a string array read from the corresponding "planes.dat" csv file into the given PlaneRecord object.
+func (r *PlaneRecord) Convert(s []string) error {
+	l := len(s)
+	if l < 3 {
+		return fmt.Errorf("Invalid field count for Plane record: %d/%d",l,3)
+	}
+	r.Name = field(s[0])
+	r.IATA = field(s[1])
+	r.ICAO = field(s[2])
+	return nil
+}
+
+// LoadPlaneData reads the plane data from the given source.
+// The source could be either a localfile or http based URL and may be gzip compressed.
+// An error is returned if the source cannot be read.
+// Planes without IATA code are not added to the PlanesByIATA index.
+func (d *Database) LoadPlaneData(source string) error {
+	return d.LoadPlaneDataContext(context.Background(),source)
+}
+
+// LoadPlaneDataContext reads the plane data from the given source like
+// LoadPlaneData does. Http requests are aborted once the given context is done.
+func (d *Database) LoadPlaneDataContext(ctx context.Context, source string) error {
+	d.logf("Loading Plane data from \"%s\"",source)
+	data,err := d.loadCsvContext(ctx,source)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.setPlaneData(source,data)
+	return nil
+}
+
+// LoadPlanesFrom reads the plane data from the given reader like LoadPlaneData does.
+// It allows to load embedded or in-memory data. The reader may be gzip compressed.
+func (d *Database) LoadPlanesFrom(r io.Reader) error {
+	data,err := d.readCsv(r)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.setPlaneData("",data)
+	return nil
+}
+
+// setPlaneData replaces the planes by the given csv lines read from source.
+// The caller must hold the write lock.
+func (d *Database) setPlaneData(source string, data [][]string) {
+	d.Planes = make([]PlaneRecord,len(data))
+	d.clearWarnings("Plane")
+	n := d.convertRecords("Plane",data,
+		func(n int) Record { return &d.Planes[n] },
+		func(dst, src int) { d.Planes[dst] = d.Planes[src] },
+		func(r Record, i int) bool { return true })
+	d.Planes = d.Planes[:n]
+	d.report.Planes = FileReport{source,n,len(data) - n}
+	d.reindexPlanes()
+}
+
+// reindexPlanes rebuilds the plane index from the Planes slice.
+func (d *Database) reindexPlanes() {
+	d.PlanesByIATA = make(map[string]*PlaneRecord)
+	for i := range d.Planes {
+		if p := &d.Planes[i]; isCode(p.IATA) {
+			d.PlanesByIATA[normalizeCode(p.IATA)] = p
+		}
+	}
+}
+
+// PlaneByIATA returns the PlaneRecord of the given IATA aircraft type code ignoring case.
+func (d *Database) PlaneByIATA(code string) *PlaneRecord {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.PlanesByIATA[normalizeCode(code)]
+}
+
+// EquipmentNames returns the names of the aircraft types of the route using the planes
+// of the given database. Codes without known plane are returned as they are.
+// An empty slice is returned if no equipment is specified.
+func (r *RouteRecord) EquipmentNames(d *Database) []string {
+	codes := r.EquipmentCodes()
+	for i,code := range codes {
+		if p := d.PlaneByIATA(code); p != nil {
+			codes[i] = p.Name
+		}
+	}
+	return codes
+}
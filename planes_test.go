@@ -0,0 +1,34 @@
+package gopenflights
+
+import(
+	"testing"
+)
+
+func TestEquipmentNames(t *testing.T) {
+	tdb,err := Open(WithAirportsFile("testdata/airports.dat"),WithRoutesFile("testdata/routes.dat"),WithAirlinesFile("testdata/airlines.dat"),WithPlanesFile("testdata/planes.dat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tdb.Planes) != 10 || len(tdb.PlanesByIATA) != 9 {
+		t.Errorf("Unexpected plane counts: %d/%d",len(tdb.Planes),len(tdb.PlanesByIATA))
+	}
+	if p := tdb.PlaneByIATA("320"); p == nil || p.Name != "Airbus A320" || p.ICAO != "A320" {
+		t.Errorf("Unexpected plane record of 320: %v",p)
+	}
+
+	r := &RouteRecord{Equipment: "744 777 XYZ"}
+	names := r.EquipmentNames(tdb)
+	if len(names) != 3 || names[0] != "Boeing 747-400" || names[1] != "Boeing 777" || names[2] != "XYZ" {
+		t.Errorf("Unexpected equipment names: %v",names)
+	}
+	for _,r := range tdb.AllRoutes() {
+		for _,n := range r.EquipmentNames(tdb) {
+			if tdb.PlaneByIATA(n) != nil {
+				t.Errorf("Equipment %s of route %s -> %s has not been resolved.",n,r.SourceAirport,r.DestAirport)
+			}
+		}
+	}
+	if r = (&RouteRecord{}); len(r.EquipmentNames(tdb)) != 0 {
+		t.Errorf("Expected no equipment names.")
+	}
+}
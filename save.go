@@ -0,0 +1,149 @@
+package gopenflights
+
+import(
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Column counts of the airport schema versions supported by SaveAirportDataSchema.
+const (
+	// AirportSchemaLegacy is the original schema without Tz, Type and Source.
+	AirportSchemaLegacy = 11
+	// AirportSchemaModern is the current schema of openflights.
+	AirportSchemaModern = 14
+)
+
+// nullField returns the csv field of the given value. Empty values are written as null.
+func nullField(s string) string {
+	if s == "" {
+		return null
+	}
+	return s
+}
+
+// formatFloat returns the shortest csv field representing the given float.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f,'f',-1,64)
+}
+
+// flag returns the "Y"/"N" csv field of the given bool.
+func flag(b bool) string {
+	if b {
+		return "Y"
+	}
+	return "N"
+}
+
+// fields returns the csv fields of the airport in the modern 14 column schema.
+func (r *AirportRecord) fields() []string {
+	dst := null
+	if r.DST != 0 {
+		dst = string(rune(r.DST))
+	}
+	return []string{
+		strconv.Itoa(r.Id),
+		nullField(r.Name),
+		nullField(r.City),
+		nullField(r.Country),
+		nullField(r.IATA),
+		nullField(r.ICAO),
+		formatFloat(r.Lat),
+		formatFloat(r.Long),
+		formatFloat(r.Alt),
+		formatFloat(r.Timezone),
+		dst,
+		nullField(r.Tz),
+		nullField(r.Type),
+		nullField(r.Source),
+	}
+}
+
+// fields returns the csv fields of the airline.
+func (r *AirlineRecord) fields() []string {
+	return []string{
+		strconv.Itoa(r.Id),
+		nullField(r.Name),
+		nullField(r.Alias),
+		nullField(r.IATA),
+		nullField(r.ICAO),
+		nullField(r.Callsign),
+		nullField(r.Country),
+		flag(r.Active),
+	}
+}
+
+// fields returns the csv fields of the route.
+// Codes dropped by CompactRoutes are taken from the referenced records.
+// The codeshare field is left empty for routes that are no codeshare like openflights does.
+func (r *RouteRecord) fields() []string {
+	cs := ""
+	if r.Codeshare {
+		cs = "Y"
+	}
+	return []string{
+		nullField(r.AirlineCode()),
+		strconv.Itoa(r.AirlineId),
+		nullField(r.SourceCode()),
+		strconv.Itoa(r.SourceAirportId),
+		nullField(r.DestCode()),
+		strconv.Itoa(r.DestAirportId),
+		cs,
+		strconv.Itoa(r.Stops),
+		r.Equipment,
+	}
+}
+
+// saveCsv writes n records to the given file. The fields of the records are retrieved by at.
+func saveCsv(path string, n int, at func(int) []string) error {
+	f,err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	for i := 0; i < n; i++ {
+		if err = w.Write(at(i)); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	w.Flush()
+	if err = w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// SaveAirportData writes all airports to the given file in the "airports.dat" csv format.
+// Airports are written in the schema of the loaded airport data. If the schema is not
+// known like for airports decoded from JSON, the modern schema is used.
+func (d *Database) SaveAirportData(path string) error {
+	schema := d.airportSchema
+	if schema == 0 {
+		schema = AirportSchemaModern
+	}
+	return d.SaveAirportDataSchema(path,schema)
+}
+
+// SaveAirportDataSchema writes all airports to the given file in the "airports.dat" csv
+// format using the given schema version AirportSchemaLegacy or AirportSchemaModern.
+// The Tz, Type and Source fields are dropped by the legacy schema.
+// An error is returned for any other schema.
+func (d *Database) SaveAirportDataSchema(path string, schema int) error {
+	if schema != AirportSchemaLegacy && schema != AirportSchemaModern {
+		return fmt.Errorf("Unsupported airport schema: %d columns",schema)
+	}
+	return saveCsv(path,len(d.Airports),func(i int) []string { return d.Airports[i].fields()[:schema] })
+}
+
+// SaveAirlineData writes all airlines to the given file in the "airlines.dat" csv format.
+func (d *Database) SaveAirlineData(path string) error {
+	return saveCsv(path,len(d.Airlines),func(i int) []string { return d.Airlines[i].fields() })
+}
+
+// SaveRouteData writes all routes to the given file in the "routes.dat" csv format.
+func (d *Database) SaveRouteData(path string) error {
+	return saveCsv(path,len(d.Routes),func(i int) []string { return d.Routes[i].fields() })
+}
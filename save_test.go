@@ -0,0 +1,97 @@
+package gopenflights
+
+import(
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveData(t *testing.T) {
+	tdb := loadTestDatabase()
+	dir := t.TempDir()
+	ap,al,rt := filepath.Join(dir,"airports.dat"),filepath.Join(dir,"airlines.dat"),filepath.Join(dir,"routes.dat")
+	if err := tdb.SaveAirportData(ap); err != nil {
+		t.Fatal(err)
+	}
+	if err := tdb.SaveAirlineData(al); err != nil {
+		t.Fatal(err)
+	}
+	if err := tdb.SaveRouteData(rt); err != nil {
+		t.Fatal(err)
+	}
+
+	sdb,err := Open(WithAirportsFile(ap),WithRoutesFile(rt),WithAirlinesFile(al))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sdb.Airports) != len(tdb.Airports) || len(sdb.Airlines) != len(tdb.Airlines) || len(sdb.Routes) != len(tdb.Routes) {
+		t.Fatalf("Unexpected record counts: %d/%d/%d",len(sdb.Airports),len(sdb.Airlines),len(sdb.Routes))
+	}
+	for i := range tdb.Airports {
+		if !reflect.DeepEqual(sdb.Airports[i].fields(),tdb.Airports[i].fields()) {
+			t.Errorf("Airport %d differs: %v",i,sdb.Airports[i].fields())
+		}
+	}
+	for i := range tdb.Airlines {
+		if !reflect.DeepEqual(sdb.Airlines[i].fields(),tdb.Airlines[i].fields()) {
+			t.Errorf("Airline %d differs: %v",i,sdb.Airlines[i].fields())
+		}
+	}
+	for i := range tdb.Routes {
+		if !reflect.DeepEqual(sdb.Routes[i].fields(),tdb.Routes[i].fields()) {
+			t.Errorf("Route %d differs: %v",i,sdb.Routes[i].fields())
+		}
+	}
+	if a := sdb.AirportByIATA("SYD"); a == nil || a.DST != 'O' || a.Tz != "Australia/Sydney" {
+		t.Errorf("Unexpected saved airport: %v",a)
+	}
+}
+
+func TestSaveAirportDataSchema(t *testing.T) {
+	tdb := loadTestDatabase()
+	dir := t.TempDir()
+	columns := func(path string) int {
+		data,err := new(Database).loadCsv(path)
+		if err != nil || len(data) == 0 {
+			t.Fatalf("Could not read saved airports: %v",err)
+		}
+		return len(data[0])
+	}
+
+	legacy := filepath.Join(dir,"legacy.dat")
+	if err := tdb.SaveAirportDataSchema(legacy,AirportSchemaLegacy); err != nil {
+		t.Fatal(err)
+	}
+	if n := columns(legacy); n != 11 {
+		t.Errorf("Expected 11 columns but got %d",n)
+	}
+	// Airports loaded from the legacy schema are saved in it by default.
+	ldb := new(Database)
+	if err := ldb.LoadAirportData(legacy); err != nil {
+		t.Fatal(err)
+	}
+	if a := ldb.AirportByIATA("SYD"); a == nil || a.Tz != "" || a.DST != 'O' {
+		t.Errorf("Unexpected legacy airport: %v",a)
+	}
+	resaved := filepath.Join(dir,"resaved.dat")
+	if err := ldb.SaveAirportData(resaved); err != nil {
+		t.Fatal(err)
+	}
+	if n := columns(resaved); n != 11 {
+		t.Errorf("Expected the legacy schema to be kept but got %d columns",n)
+	}
+
+	modern := filepath.Join(dir,"modern.dat")
+	if err := tdb.SaveAirportData(modern); err != nil {
+		t.Fatal(err)
+	}
+	if n := columns(modern); n != 14 {
+		t.Errorf("Expected 14 columns but got %d",n)
+	}
+	if err := ldb.SaveAirportDataSchema(modern,AirportSchemaModern); err != nil || columns(modern) != 14 {
+		t.Errorf("Expected legacy airports to be saved in the modern schema: %v",err)
+	}
+	if err := tdb.SaveAirportDataSchema(modern,12); err == nil {
+		t.Errorf("Expected an error for an unsupported schema.")
+	}
+}
@@ -0,0 +1,255 @@
+package gopenflights
+
+import(
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// routeCount returns the number of routes from and to the airport.
+func (a *AirportRecord) routeCount() int {
+	return len(a.DestRoutes) + len(a.SourceRoutes)
+}
+
+// OrphanAirports returns all airports without any route from or to them in the order
+// of the Airports slice.
+func (d *Database) OrphanAirports() (ret []*AirportRecord) {
+	for i := range d.Airports {
+		if d.Airports[i].routeCount() == 0 {
+			ret = append(ret,&d.Airports[i])
+		}
+	}
+	return
+}
+
+// AirportsOfType returns all airports of the given type like AirportTypeStation ignoring
+// case in the order of the Airports slice.
+func (d *Database) AirportsOfType(t string) (ret []*AirportRecord) {
+	for i := range d.Airports {
+		if strings.EqualFold(d.Airports[i].Type,t) {
+			ret = append(ret,&d.Airports[i])
+		}
+	}
+	return
+}
+
+// BusiestAirports returns the n airports with the most routes from and to them sorted
+// by descending route count. Airports with equal route counts are ordered by id.
+// If n exceeds the number of airports, all airports are returned.
+func (d *Database) BusiestAirports(n int) []*AirportRecord {
+	ret := make([]*AirportRecord,len(d.Airports))
+	for i := range d.Airports {
+		ret[i] = &d.Airports[i]
+	}
+	sort.Slice(ret,func(i,j int) bool {
+		ci,cj := ret[i].routeCount(),ret[j].routeCount()
+		if ci != cj {
+			return ci > cj
+		}
+		return ret[i].Id < ret[j].Id
+	})
+	if n < 0 {
+		n = 0
+	}
+	if n < len(ret) {
+		ret = ret[:n]
+	}
+	return ret
+}
+
+// AirlineEquipment returns how often each aircraft type code occurs on the routes of
+// the given airline id. If skipCodeshare is set, codeshare routes are not counted.
+func (d *Database) AirlineEquipment(airlineId int, skipCodeshare bool) map[string]int {
+	ret := make(map[string]int)
+	for _,r := range d.RoutesByAirline(airlineId) {
+		if skipCodeshare && r.Codeshare {
+			continue
+		}
+		for _,code := range r.EquipmentCodes() {
+			ret[code]++
+		}
+	}
+	return ret
+}
+
+// CountryRouteMatrix returns the number of routes from each source country to each
+// destination country. Domestic routes are counted on the diagonal.
+// Routes with unresolved airports are skipped.
+func (d *Database) CountryRouteMatrix() map[string]map[string]int {
+	ret := make(map[string]map[string]int)
+	for i := range d.Routes {
+		s,t := d.Routes[i].SourceAirportP,d.Routes[i].DestAirportP
+		if s == nil || t == nil {
+			continue
+		}
+		if ret[s.Country] == nil {
+			ret[s.Country] = make(map[string]int)
+		}
+		ret[s.Country][t.Country]++
+	}
+	return ret
+}
+
+// Stats summarizes the contents of a Database.
+type Stats struct {
+	Airports int
+	Airlines int
+	// ActiveAirlines is the number of airlines flagged as active.
+	ActiveAirlines int
+	Routes int
+	// UniqueRoutes is the number of distinct source and destination airport pairs of all routes.
+	UniqueRoutes int
+	// Countries is the number of distinct countries of all airports.
+	Countries int
+	// DroppedRoutes is the number of routes skipped while loading the route data.
+	DroppedRoutes int
+}
+
+// Stats returns a summary of the record counts of the database.
+func (d *Database) Stats() Stats {
+	type pair struct {
+		src,dst int
+	}
+	pairs := make(map[pair]bool,len(d.Routes))
+	for i := range d.Routes {
+		pairs[pair{d.Routes[i].SourceAirportId,d.Routes[i].DestAirportId}] = true
+	}
+	countries := make(map[string]bool)
+	for i := range d.Airports {
+		countries[d.Airports[i].Country] = true
+	}
+	return Stats{
+		Airports: len(d.Airports),
+		Airlines: len(d.Airlines),
+		ActiveAirlines: len(d.ActiveAirlines()),
+		Routes: len(d.Routes),
+		UniqueRoutes: len(pairs),
+		Countries: len(countries),
+		DroppedRoutes: d.report.Routes.Skipped,
+	}
+}
+
+// AirlineStats summarizes the routes of a single airline.
+type AirlineStats struct {
+	Routes int
+	// Sources is the number of distinct source airports of the routes.
+	Sources int
+	// Destinations is the number of distinct destination airports of the routes.
+	Destinations int
+	// Countries is the number of distinct countries of the resolved source and destination airports.
+	Countries int
+	// Codeshares is the number of codeshare routes, Operated the number of routes operated by the airline itself.
+	Codeshares int
+	Operated int
+}
+
+// AirlineStats returns a summary of the routes of the given airline id.
+// Airports are counted by id, so unresolved airports are included in Sources and Destinations.
+func (d *Database) AirlineStats(airlineId int) AirlineStats {
+	routes := d.RoutesByAirline(airlineId)
+	sources := make(map[int]bool)
+	dests := make(map[int]bool)
+	countries := make(map[string]bool)
+	ret := AirlineStats{Routes: len(routes)}
+	for _,r := range routes {
+		sources[r.SourceAirportId] = true
+		dests[r.DestAirportId] = true
+		for _,a := range []*AirportRecord{r.SourceAirportP,r.DestAirportP} {
+			if a != nil {
+				countries[a.Country] = true
+			}
+		}
+		if r.Codeshare {
+			ret.Codeshares++
+		} else {
+			ret.Operated++
+		}
+	}
+	ret.Sources = len(sources)
+	ret.Destinations = len(dests)
+	ret.Countries = len(countries)
+	return ret
+}
+
+// CityPair is a directional pair of airports with the number of airlines operating routes between them.
+type CityPair struct {
+	SourceAirportId int
+	DestAirportId int
+	Airlines int
+}
+
+// TopCityPairs returns the n directional airport pairs served by the most distinct
+// airlines sorted by descending airline count. Pairs with equal counts are ordered by
+// source and destination airport id. Codeshare routes are not counted, since they are
+// operated by another airline of the same pair. Routes with unresolved airports are skipped.
+// If n exceeds the number of pairs, all pairs are returned.
+func (d *Database) TopCityPairs(n int) []CityPair {
+	type pair struct {
+		src,dst int
+	}
+	airlines := make(map[pair]map[int]bool)
+	for i := range d.Routes {
+		r := &d.Routes[i]
+		if r.Codeshare || r.SourceAirportP == nil || r.DestAirportP == nil {
+			continue
+		}
+		p := pair{r.SourceAirportId,r.DestAirportId}
+		if airlines[p] == nil {
+			airlines[p] = make(map[int]bool)
+		}
+		airlines[p][r.AirlineId] = true
+	}
+	ret := make([]CityPair,0,len(airlines))
+	for p,a := range airlines {
+		ret = append(ret,CityPair{p.src,p.dst,len(a)})
+	}
+	sort.Slice(ret,func(i,j int) bool {
+		if ret[i].Airlines != ret[j].Airlines {
+			return ret[i].Airlines > ret[j].Airlines
+		}
+		if ret[i].SourceAirportId != ret[j].SourceAirportId {
+			return ret[i].SourceAirportId < ret[j].SourceAirportId
+		}
+		return ret[i].DestAirportId < ret[j].DestAirportId
+	})
+	if n < 0 {
+		n = 0
+	}
+	if n < len(ret) {
+		ret = ret[:n]
+	}
+	return ret
+}
+
+// IsInternational reports whether the source and destination airport of the route are
+// located in different countries. False is returned if an airport is not resolved.
+func (r *RouteRecord) IsInternational() bool {
+	return r.SourceAirportP != nil && r.DestAirportP != nil && r.SourceAirportP.Country != r.DestAirportP.Country
+}
+
+// InternationalRatio returns the fraction (0-1) of the departing and arriving routes of
+// the given airport id that are international. Routes with unresolved airports are not
+// counted. If the airport has no such routes, 0 is returned.
+// An error is returned if the airport is unknown.
+func (d *Database) InternationalRatio(airportId int) (float64, error) {
+	ap := d.Airport(airportId)
+	if ap == nil {
+		return 0,fmt.Errorf("Unknown airportId: %d",airportId)
+	}
+	total,international := 0,0
+	for _,routes := range []map[*RouteRecord]bool{ap.SourceRoutes,ap.DestRoutes} {
+		for r := range routes {
+			if r.SourceAirportP == nil || r.DestAirportP == nil {
+				continue
+			}
+			total++
+			if r.IsInternational() {
+				international++
+			}
+		}
+	}
+	if total == 0 {
+		return 0,nil
+	}
+	return float64(international) / float64(total),nil
+}
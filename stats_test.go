@@ -0,0 +1,154 @@
+package gopenflights
+
+import(
+	"math"
+	"testing"
+)
+
+func TestBusiestAirports(t *testing.T) {
+	tdb := loadTestDatabase()
+	top := tdb.BusiestAirports(2)
+	if len(top) != 2 || top[0].IATA != "JFK" || top[1].IATA != "FRA" {
+		t.Errorf("Expected JFK and FRA to be the busiest airports.")
+	}
+	if all := tdb.BusiestAirports(100); len(all) != len(tdb.Airports) {
+		t.Errorf("Expected all %d airports but got %d",len(tdb.Airports),len(all))
+	}
+}
+
+func TestOrphanAirports(t *testing.T) {
+	tdb := loadTestDatabase()
+	// Goroka and Frankfurt Hauptbahnhof have no routes.
+	orphans := tdb.OrphanAirports()
+	if len(orphans) != 2 || orphans[0].Id != 1 || orphans[1].Id != 8950 {
+		t.Errorf("Unexpected orphan airports: %v",orphans)
+	}
+}
+
+func TestAirlineEquipment(t *testing.T) {
+	tdb := loadTestDatabase()
+	eq := tdb.AirlineEquipment(24,false)
+	if eq["777"] != 2 || eq["744"] != 1 || eq["321"] != 2 {
+		t.Errorf("Unexpected equipment of American Airlines: %v",eq)
+	}
+	// AA LHR -> JFK is a codeshare.
+	if eq = tdb.AirlineEquipment(24,true); eq["777"] != 1 || eq["744"] != 0 {
+		t.Errorf("Unexpected equipment of American Airlines without codeshares: %v",eq)
+	}
+}
+
+func TestCountryRouteMatrix(t *testing.T) {
+	tdb := loadTestDatabase()
+	m := tdb.CountryRouteMatrix()
+	total := 0
+	for _,row := range m {
+		for _,n := range row {
+			total += n
+		}
+	}
+	// LH FRA -> QQQ has an unknown destination.
+	if total != len(tdb.Routes) - 1 {
+		t.Errorf("Expected %d routes but got %d",len(tdb.Routes) - 1,total)
+	}
+	// AA and BA fly LHR -> JFK.
+	if n := m["United Kingdom"]["United States"]; n != 2 {
+		t.Errorf("Expected 2 routes from the United Kingdom to the United States but got %d",n)
+	}
+	if n := m["United States"]["United States"]; n != 2 {
+		t.Errorf("Expected 2 domestic routes in the United States but got %d",n)
+	}
+}
+
+func TestStats(t *testing.T) {
+	tdb := loadTestDatabase()
+	// LHR <-> JFK is served by two airlines in each direction. FRA -> XYZ is dropped.
+	exp := Stats{Airports: 9, Airlines: 8, ActiveAirlines: 7, Routes: 20, UniqueRoutes: 18, Countries: 6, DroppedRoutes: 1}
+	if s := tdb.Stats(); s != exp {
+		t.Errorf("Unexpected stats: %+v",s)
+	}
+}
+
+func TestAirportsOfType(t *testing.T) {
+	tdb := loadTestDatabase()
+	if st := tdb.AirportsOfType(AirportTypeStation); len(st) != 1 || st[0].Id != 8950 {
+		t.Errorf("Expected Frankfurt Hauptbahnhof to be the only station but got %v",st)
+	}
+	if ap := tdb.AirportsOfType("Airport"); len(ap) != 8 {
+		t.Errorf("Expected 8 airports but got %d",len(ap))
+	}
+
+	tdb,err := Open(WithAirportsFile("testdata/airports.dat"),WithRoutesFile("testdata/routes.dat"),WithAirlinesFile("testdata/airlines.dat"),WithAirportTypes(AirportTypeAirport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tdb.Airports) != 8 || tdb.Airport(8950) != nil || tdb.report.Airports.Skipped != 1 {
+		t.Errorf("Expected the station to be skipped.")
+	}
+}
+
+func TestAirlineStats(t *testing.T) {
+	tdb := loadTestDatabase()
+	// The Lufthansa route to QQQ has an unknown destination airport.
+	if s := tdb.AirlineStats(3090); s != (AirlineStats{Routes: 6, Sources: 3, Destinations: 5, Countries: 3, Operated: 6}) {
+		t.Errorf("Unexpected stats of Lufthansa: %+v",s)
+	}
+	if s := tdb.AirlineStats(24); s != (AirlineStats{Routes: 4, Sources: 3, Destinations: 3, Countries: 2, Codeshares: 1, Operated: 3}) {
+		t.Errorf("Unexpected stats of American Airlines: %+v",s)
+	}
+	if s := tdb.AirlineStats(12345); s != (AirlineStats{}) {
+		t.Errorf("Expected empty stats for an unknown airline but got %+v",s)
+	}
+}
+
+func TestTopCityPairs(t *testing.T) {
+	tdb := loadTestDatabase()
+	// JFK -> LHR is operated by AA and BA. AA LHR -> JFK is a codeshare.
+	top := tdb.TopCityPairs(2)
+	if len(top) != 2 || top[0] != (CityPair{3797,507,2}) || top[1] != (CityPair{340,345,1}) {
+		t.Errorf("Unexpected top city pairs: %v",top)
+	}
+	all := tdb.TopCityPairs(100)
+	for _,p := range all {
+		if p.SourceAirportId == 507 && p.DestAirportId == 3797 && p.Airlines != 1 {
+			t.Errorf("Expected the codeshare on LHR -> JFK not to be counted but got %d airlines",p.Airlines)
+		}
+		if tdb.Airport(p.DestAirportId) == nil {
+			t.Errorf("Unexpected pair with unknown destination: %v",p)
+		}
+	}
+	// 20 routes minus the codeshare, the duplicate JFK -> LHR and FRA -> QQQ.
+	if len(all) != 17 {
+		t.Errorf("Expected 17 city pairs but got %d",len(all))
+	}
+	if len(tdb.TopCityPairs(-1)) != 0 {
+		t.Errorf("Expected no city pairs for negative n.")
+	}
+}
+
+func TestInternationalRatio(t *testing.T) {
+	tdb := loadTestDatabase()
+	if r := tdb.Route(3090,340,345); r == nil || r.IsInternational() {
+		t.Errorf("Expected FRA -> DUS to be domestic.")
+	}
+	if r := tdb.Route(3090,340,3797); r == nil || !r.IsInternational() {
+		t.Errorf("Expected FRA -> JFK to be international.")
+	}
+	if r := tdb.Route(3090,340,99999); r == nil || r.IsInternational() {
+		t.Errorf("Expected FRA -> QQQ with unknown destination not to be international.")
+	}
+
+	// FRA -> QQQ is not counted. FRA <-> DUS are the only domestic routes.
+	ratio,err := tdb.InternationalRatio(340)
+	if err != nil || math.Abs(ratio - 5.0 / 7.0) > 1e-9 {
+		t.Errorf("Unexpected international ratio of FRA: %f (%v)",ratio,err)
+	}
+	if ratio,err = tdb.InternationalRatio(3361); err != nil || ratio != 1 {
+		t.Errorf("Unexpected international ratio of SYD: %f (%v)",ratio,err)
+	}
+	if ratio,err = tdb.InternationalRatio(1); err != nil || ratio != 0 {
+		t.Errorf("Expected 0 for an airport without routes: %f (%v)",ratio,err)
+	}
+	if _,err = tdb.InternationalRatio(123456); err == nil {
+		t.Errorf("Expected an error for an unknown airport.")
+	}
+}
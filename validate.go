@@ -0,0 +1,47 @@
+package gopenflights
+
+import(
+	"fmt"
+	"math"
+)
+
+// Validate checks the integrity of the loaded data and returns an error for each problem found.
+// It reports routes with unresolved source airport, destination airport or airline
+// (including placeholder airlines), airports with missing or out of range coordinates
+// and IATA codes shared by several airports. Records that could not be converted at all are not part of the
+// database and are only listed as warnings of the load report.
+// An empty slice is returned if no problems have been found.
+func (d *Database) Validate() []error {
+	errs := []error{}
+	for i := range d.Routes {
+		r := &d.Routes[i]
+		if r.SourceAirportP == nil {
+			errs = append(errs,fmt.Errorf("Route %d %s -> %s: Unknown source airportId %d.",i,r.SourceCode(),r.DestCode(),r.SourceAirportId))
+		}
+		if r.DestAirportP == nil {
+			errs = append(errs,fmt.Errorf("Route %d %s -> %s: Unknown destination airportId %d.",i,r.SourceCode(),r.DestCode(),r.DestAirportId))
+		}
+		if d.AirlinesByIdIndex[r.AirlineId] == nil {
+			errs = append(errs,fmt.Errorf("Route %d %s -> %s: Unknown airlineId %d/%s.",i,r.SourceCode(),r.DestCode(),r.AirlineId,r.AirlineCode()))
+		}
+	}
+
+	seen := make(map[string]*AirportRecord)
+	for i := range d.Airports {
+		a := &d.Airports[i]
+		if math.IsNaN(a.Lat) || math.IsNaN(a.Long) || math.Abs(a.Lat) > 90 || math.Abs(a.Long) > 180 {
+			errs = append(errs,fmt.Errorf("Airport %d: Invalid coordinates %f/%f.",a.Id,a.Lat,a.Long))
+		} else if !a.hasPosition() {
+			errs = append(errs,fmt.Errorf("Airport %d: Coordinates are not specified.",a.Id))
+		}
+		if !isCode(a.IATA) {
+			continue
+		}
+		if prev := seen[a.IATA]; prev != nil {
+			errs = append(errs,fmt.Errorf("Airport %d: IATA code %s is already used by airport %d.",a.Id,a.IATA,prev.Id))
+		} else {
+			seen[a.IATA] = a
+		}
+	}
+	return errs
+}
@@ -0,0 +1,24 @@
+package gopenflights
+
+import(
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tdb := loadTestDatabase()
+	// The ZZ route has an unknown airline, LH FRA -> QQQ an unknown destination.
+	errs := tdb.Validate()
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 problems but got %d: %v",len(errs),errs)
+	}
+	if !strings.Contains(errs[0].Error(),"airlineId 9999") || !strings.Contains(errs[1].Error(),"airportId 99999") {
+		t.Errorf("Unexpected problems: %v",errs)
+	}
+
+	tdb.Airports[0].IATA = "FRA"
+	tdb.Airports[1].Lat = 91
+	if errs = tdb.Validate(); len(errs) != 4 {
+		t.Errorf("Expected 4 problems but got %d: %v",len(errs),errs)
+	}
+}